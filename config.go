@@ -0,0 +1,365 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+// Config describes a serial port configuration.
+// It may be loaded from JSON, YAML or TOML and applied with OpenWithConfig() or Port.ApplyConfig(),
+// options are applied on top of the config as well (see Option type).
+type Config struct {
+	// The serial port bitrate (aka Baudrate).
+	BaudRate int `json:"baud_rate" yaml:"baud_rate" toml:"baud_rate"`
+	// Size of the character (must be 5, 6, 7 or 8).
+	DataBits int `json:"data_bits" yaml:"data_bits" toml:"data_bits"`
+	// Parity (see Parity type for more info).
+	Parity Parity `json:"parity" yaml:"parity" toml:"parity"`
+	// Stop bits (see StopBits type for more info).
+	StopBits StopBits `json:"stop_bits" yaml:"stop_bits" toml:"stop_bits"`
+	// Hardware flow control (see FlowControl type for more info).
+	FlowControl FlowControl `json:"flow_control,omitempty" yaml:"flow_control,omitempty" toml:"flow_control,omitempty"`
+	// Software flow control (see SoftwareFlowControl type for more info).
+	SoftwareFlowControl SoftwareFlowControl `json:"software_flow_control,omitempty" yaml:"software_flow_control,omitempty" toml:"software_flow_control,omitempty"` //nolint:lll
+	// Read timeout in milliseconds (see WithReadTimeout).
+	ReadTimeout int `json:"read_timeout,omitempty" yaml:"read_timeout,omitempty" toml:"read_timeout,omitempty"`
+	// Write timeout in milliseconds (see WithWriteTimeout).
+	WriteTimeout int `json:"write_timeout,omitempty" yaml:"write_timeout,omitempty" toml:"write_timeout,omitempty"`
+	// Lower DTR line on close (hang up).
+	HUPCL bool `json:"hupcl,omitempty" yaml:"hupcl,omitempty" toml:"hupcl,omitempty"`
+	// RS-485 mode (see RS485Config type for more info).
+	RS485 RS485Config `json:"rs485,omitempty" yaml:"rs485,omitempty" toml:"rs485,omitempty"`
+	// Exclusive access mode (see ExclusiveMode type for more info).
+	Exclusive ExclusiveMode `json:"exclusive,omitempty" yaml:"exclusive,omitempty" toml:"exclusive,omitempty"`
+	// Create UUCP lock file on open (see WithUUCPLock).
+	UUCPLock bool `json:"uucp_lock,omitempty" yaml:"uucp_lock,omitempty" toml:"uucp_lock,omitempty"`
+	// Directory of UUCP lock files, DefaultUUCPLockDir if empty (see WithUUCPLockDir).
+	UUCPLockDir string `json:"uucp_lock_dir,omitempty" yaml:"uucp_lock_dir,omitempty" toml:"uucp_lock_dir,omitempty"`
+	// Restore the original device settings on close (see WithRestoreOnClose).
+	RestoreOnClose bool `json:"restore_on_close,omitempty" yaml:"restore_on_close,omitempty" toml:"restore_on_close,omitempty"`
+	// Report line errors within the data stream (see WithLineErrorReporting).
+	LineErrorReporting bool `json:"line_error_reporting,omitempty" yaml:"line_error_reporting,omitempty" toml:"line_error_reporting,omitempty"` //nolint:lll
+}
+
+// DefaultConfig returns the configuration used by Open() if no options given: 9600 8N1 without flow control
+// and with platform default timeouts.
+func DefaultConfig() Config {
+	return Config{
+		BaudRate:     9600,
+		DataBits:     8,
+		Parity:       NoParity,
+		StopBits:     OneStopBit,
+		ReadTimeout:  defaultReadTimeout,
+		WriteTimeout: defaultWriteTimeout,
+	}
+}
+
+// NewConfig returns the default configuration modified by the given options.
+func NewConfig(opts ...Option) Config {
+	c := DefaultConfig()
+	for _, o := range opts {
+		o(&c)
+	}
+	return c
+}
+
+// Validate checks that the configuration is valid and supported by the platform.
+// Every violation found is reported as PortError with the corresponding code,
+// multiple errors are combined (see go.uber.org/multierr.Errors).
+//
+//nolint:cyclop
+func (c Config) Validate() error {
+	caps := platformCapabilities
+
+	var err error
+	if c.BaudRate <= 0 || (caps.baudRate != nil && !caps.baudRate(c.BaudRate)) {
+		err = multierr.Append(err, newConfigError(InvalidSpeed, "BaudRate", c.BaudRate))
+	}
+	if c.DataBits < 5 || c.DataBits > 8 {
+		err = multierr.Append(err, newConfigError(InvalidDataBits, "DataBits", c.DataBits))
+	}
+	switch c.Parity {
+	case NoParity, OddParity, EvenParity:
+	case MarkParity, SpaceParity:
+		if !caps.markSpaceParity {
+			err = multierr.Append(err, newConfigError(InvalidParity, "Parity", c.Parity))
+		}
+	default:
+		err = multierr.Append(err, newConfigError(InvalidParity, "Parity", c.Parity))
+	}
+	switch c.StopBits {
+	case OneStopBit, TwoStopBits:
+	case OnePointFiveStopBits:
+		if !caps.onePointFiveStopBits {
+			err = multierr.Append(err, newConfigError(InvalidStopBits, "StopBits", c.StopBits))
+		}
+	default:
+		err = multierr.Append(err, newConfigError(InvalidStopBits, "StopBits", c.StopBits))
+	}
+	switch c.FlowControl {
+	case NoFlowControl:
+	case RTSCTSFlowControl:
+		if c.RS485.Enabled && !caps.rs485WithRTSCTS {
+			err = multierr.Append(err, newConfigError(InvalidFlowControl, "FlowControl", "rtscts with rs485"))
+		}
+	case DTRDSRFlowControl:
+		if !caps.dtrDSRFlowControl {
+			err = multierr.Append(err, newConfigError(InvalidFlowControl, "FlowControl", c.FlowControl))
+		}
+	default:
+		err = multierr.Append(err, newConfigError(InvalidFlowControl, "FlowControl", c.FlowControl))
+	}
+	if c.SoftwareFlowControl.Any && !caps.softwareFlowControlAny {
+		err = multierr.Append(err, newConfigError(InvalidFlowControl, "SoftwareFlowControl.Any", true))
+	}
+	if fc := c.SoftwareFlowControl; (fc.Input || fc.Output) && fc.xon() == fc.xoff() {
+		err = multierr.Append(err, newConfigError(InvalidFlowControl, "SoftwareFlowControl", "xon equal to xoff"))
+	}
+	if c.RS485.DelayRTSBeforeSend < 0 {
+		err = multierr.Append(err, newConfigError(InvalidTimeoutValue, "RS485.DelayRTSBeforeSend", c.RS485.DelayRTSBeforeSend))
+	}
+	if c.RS485.DelayRTSAfterSend < 0 {
+		err = multierr.Append(err, newConfigError(InvalidTimeoutValue, "RS485.DelayRTSAfterSend", c.RS485.DelayRTSAfterSend))
+	}
+	switch c.Exclusive {
+	case ExclusiveTIOCEXCL:
+	case ExclusiveNone, ExclusiveFlock, ExclusiveBoth:
+		if !caps.exclusiveModes {
+			err = multierr.Append(err, newConfigError(FunctionNotImplemented, "Exclusive", c.Exclusive))
+		}
+	default:
+		err = multierr.Append(err, newConfigError(FunctionNotImplemented, "Exclusive", c.Exclusive))
+	}
+	if c.UUCPLock && !caps.uucpLock {
+		err = multierr.Append(err, newConfigError(FunctionNotImplemented, "UUCPLock", true))
+	}
+	if c.LineErrorReporting && !caps.lineErrorReporting {
+		err = multierr.Append(err, newConfigError(FunctionNotImplemented, "LineErrorReporting", true))
+	}
+	return err
+}
+
+// withOpenOnly returns c with the settings applied on open only taken from o.
+func (c Config) withOpenOnly(o Config) Config {
+	c.Exclusive, c.UUCPLock, c.UUCPLockDir, c.RestoreOnClose = o.Exclusive, o.UUCPLock, o.UUCPLockDir, o.RestoreOnClose
+	return c
+}
+
+// checkOpenOnly reports the changes of the settings applied on open only.
+func (c Config) checkOpenOnly(to Config) error {
+	var err error
+	if to.Exclusive != c.Exclusive {
+		err = multierr.Append(err, newConfigError(FunctionNotImplemented, "Exclusive", "change after open"))
+	}
+	if to.UUCPLock != c.UUCPLock {
+		err = multierr.Append(err, newConfigError(FunctionNotImplemented, "UUCPLock", "change after open"))
+	}
+	if to.UUCPLockDir != c.UUCPLockDir {
+		err = multierr.Append(err, newConfigError(FunctionNotImplemented, "UUCPLockDir", "change after open"))
+	}
+	if to.RestoreOnClose != c.RestoreOnClose {
+		err = multierr.Append(err, newConfigError(FunctionNotImplemented, "RestoreOnClose", "change after open"))
+	}
+	return err
+}
+
+func newConfigError(code PortErrorCode, field string, v interface{}) *PortError {
+	return &PortError{code: code, wrapped: fmt.Errorf("%s %v", field, v)}
+}
+
+// capabilities describes the configuration supported by the platform, see Config.Validate().
+type capabilities struct {
+	baudRate               func(r int) bool // Reports whether the baud rate supported, any positive if nil
+	markSpaceParity        bool
+	onePointFiveStopBits   bool
+	dtrDSRFlowControl      bool
+	softwareFlowControlAny bool
+	rs485WithRTSCTS        bool
+	lineErrorReporting     bool
+	exclusiveModes         bool // ExclusiveMode other than default
+	uucpLock               bool
+}
+
+// ApplyConfig validates and applies the whole configuration c to the port.
+// Timeouts are applied only if changed, so the ones set by SetReadTimeoutEx() and others are kept.
+// Nothing is changed if the configuration is invalid, the previous configuration is restored
+// if the device rejects the new one. Exclusive, UUCPLock, UUCPLockDir and RestoreOnClose take effect on open
+// only, so changing them is reported with FunctionNotImplemented code.
+func (p *Port) ApplyConfig(c Config) (err error) {
+	defer p.wrapErr("reconfigure", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.cfg.checkOpenOnly(c); err != nil {
+		return err
+	}
+	return p.applyConfig(c)
+}
+
+// applyConfig applies the validated configuration c, p.mu must be held.
+func (p *Port) applyConfig(c Config) error {
+	prev := p.cfg
+	p.applyTimeouts(prev, c)
+	p.cfg = c
+	if err := p.reconfigure(); err != nil {
+		// Roll back, the device may be reconfigured partially
+		p.applyTimeouts(c, prev)
+		p.cfg = prev
+		return multierr.Append(err, p.reconfigure())
+	}
+	return nil
+}
+
+func (p *Port) applyTimeouts(from, to Config) {
+	if to.ReadTimeout != from.ReadTimeout {
+		p.setReadTimeoutValues(to.ReadTimeout)
+	}
+	if to.WriteTimeout != from.WriteTimeout {
+		p.setWriteTimeoutValues(to.WriteTimeout)
+	}
+}
+
+// Reconfigure applies the options on top of the current port configuration, see ApplyConfig.
+func (p *Port) Reconfigure(opts ...Option) (err error) {
+	defer p.wrapErr("reconfigure", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c := p.cfg
+	for _, o := range opts {
+		o(&c)
+	}
+	if err := multierr.Append(c.Validate(), p.cfg.checkOpenOnly(c)); err != nil {
+		return err
+	}
+	return p.applyConfig(c)
+}
+
+var (
+	parityNames      = []string{NoParity: "none", OddParity: "odd", EvenParity: "even", MarkParity: "mark", SpaceParity: "space"}
+	stopBitsNames    = []string{OneStopBit: "1", OnePointFiveStopBits: "1.5", TwoStopBits: "2"}
+	flowControlNames = []string{NoFlowControl: "none", RTSCTSFlowControl: "rtscts", DTRDSRFlowControl: "dtrdsr"}
+	exclusiveNames   = []string{ExclusiveTIOCEXCL: "tiocexcl", ExclusiveNone: "none", ExclusiveFlock: "flock", ExclusiveBoth: "both"}
+)
+
+func (p Parity) String() string {
+	return enumString(parityNames, int(p), "Parity")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p Parity) MarshalText() ([]byte, error) {
+	return enumMarshalText(parityNames, int(p), InvalidParity)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, names are case insensitive.
+func (p *Parity) UnmarshalText(text []byte) error {
+	v, err := enumUnmarshalText(parityNames, text, InvalidParity)
+	if err != nil {
+		return err
+	}
+	*p = Parity(v)
+	return nil
+}
+
+func (s StopBits) String() string {
+	return enumString(stopBitsNames, int(s), "StopBits")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s StopBits) MarshalText() ([]byte, error) {
+	return enumMarshalText(stopBitsNames, int(s), InvalidStopBits)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *StopBits) UnmarshalText(text []byte) error {
+	v, err := enumUnmarshalText(stopBitsNames, text, InvalidStopBits)
+	if err != nil {
+		return err
+	}
+	*s = StopBits(v)
+	return nil
+}
+
+func (f FlowControl) String() string {
+	return enumString(flowControlNames, int(f), "FlowControl")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (f FlowControl) MarshalText() ([]byte, error) {
+	return enumMarshalText(flowControlNames, int(f), InvalidFlowControl)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, names are case insensitive.
+func (f *FlowControl) UnmarshalText(text []byte) error {
+	v, err := enumUnmarshalText(flowControlNames, text, InvalidFlowControl)
+	if err != nil {
+		return err
+	}
+	*f = FlowControl(v)
+	return nil
+}
+
+func (m ExclusiveMode) String() string {
+	return enumString(exclusiveNames, int(m), "ExclusiveMode")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (m ExclusiveMode) MarshalText() ([]byte, error) {
+	return enumMarshalText(exclusiveNames, int(m), FunctionNotImplemented)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, names are case insensitive.
+func (m *ExclusiveMode) UnmarshalText(text []byte) error {
+	v, err := enumUnmarshalText(exclusiveNames, text, FunctionNotImplemented)
+	if err != nil {
+		return err
+	}
+	*m = ExclusiveMode(v)
+	return nil
+}
+
+func enumString(names []string, v int, typ string) string {
+	if v < 0 || v >= len(names) {
+		return fmt.Sprintf("%s(%d)", typ, v)
+	}
+	return names[v]
+}
+
+func enumMarshalText(names []string, v int, code PortErrorCode) ([]byte, error) {
+	if v < 0 || v >= len(names) {
+		return nil, &PortError{code: code, wrapped: fmt.Errorf("value %d", v)}
+	}
+	return []byte(names[v]), nil
+}
+
+func enumUnmarshalText(names []string, text []byte, code PortErrorCode) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(name, string(text)) {
+			return i, nil
+		}
+	}
+	return 0, &PortError{code: code, wrapped: fmt.Errorf("unknown value %q", text)}
+}
@@ -0,0 +1,105 @@
+package serial_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
+
+	"github.com/albenik/go-serial/v2"
+)
+
+func TestConfig_JSON(t *testing.T) {
+	c := serial.NewConfig(
+		serial.WithBaudrate(115200),
+		serial.WithParity(serial.EvenParity),
+		serial.WithStopBits(serial.OnePointFiveStopBits),
+		serial.WithFlowControl(serial.RTSCTSFlowControl),
+		serial.WithReadTimeout(100),
+	)
+
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"baud_rate":115200`)
+	assert.Contains(t, string(data), `"parity":"even"`)
+	assert.Contains(t, string(data), `"stop_bits":"1.5"`)
+	assert.Contains(t, string(data), `"flow_control":"rtscts"`)
+
+	var got serial.Config
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, c, got)
+}
+
+func TestConfig_UnmarshalJSON(t *testing.T) {
+	var c serial.Config
+	err := json.Unmarshal([]byte(`{"baud_rate":9600,"data_bits":7,"parity":"ODD","stop_bits":"2"}`), &c)
+	require.NoError(t, err)
+	assert.Equal(t, serial.Config{BaudRate: 9600, DataBits: 7, Parity: serial.OddParity, StopBits: serial.TwoStopBits}, c)
+
+	err = json.Unmarshal([]byte(`{"parity":"sometimes"}`), &c)
+	var portErr *serial.PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, serial.InvalidParity, portErr.Code())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify serial.Option
+		code   serial.PortErrorCode
+	}{
+		{name: "BaudRate", modify: serial.WithBaudrate(0), code: serial.InvalidSpeed},
+		{name: "DataBitsLow", modify: serial.WithDataBits(4), code: serial.InvalidDataBits},
+		{name: "DataBitsHigh", modify: serial.WithDataBits(9), code: serial.InvalidDataBits},
+		{name: "Parity", modify: serial.WithParity(serial.Parity(42)), code: serial.InvalidParity},
+		{name: "StopBits", modify: serial.WithStopBits(serial.StopBits(-1)), code: serial.InvalidStopBits},
+		{name: "FlowControl", modify: serial.WithFlowControl(serial.FlowControl(3)), code: serial.InvalidFlowControl},
+		{
+			name:   "SoftwareFlowControl",
+			modify: serial.WithSoftwareFlowControl(serial.SoftwareFlowControl{Output: true, XOFF: serial.DefaultXON}),
+			code:   serial.InvalidFlowControl,
+		},
+		{
+			name:   "RS485Delay",
+			modify: serial.WithRS485(serial.RS485Config{Enabled: true, DelayRTSAfterSend: -1}),
+			code:   serial.InvalidTimeoutValue,
+		},
+	}
+
+	require.NoError(t, serial.DefaultConfig().Validate())
+	// The characters of the disabled flow control are not checked
+	require.NoError(t, serial.NewConfig(serial.WithSoftwareFlowControl(serial.SoftwareFlowControl{XOFF: serial.DefaultXON})).Validate())
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var portErr *serial.PortError
+			require.ErrorAs(t, serial.NewConfig(tt.modify).Validate(), &portErr)
+			assert.Equal(t, tt.code, portErr.Code())
+		})
+	}
+}
+
+func TestConfig_Validate_Aggregate(t *testing.T) {
+	err := serial.NewConfig(
+		serial.WithDataBits(9),
+		serial.WithParity(serial.Parity(42)),
+		serial.WithRS485(serial.RS485Config{DelayRTSBeforeSend: -1, DelayRTSAfterSend: -1}),
+	).Validate()
+
+	var codes []serial.PortErrorCode
+	for _, e := range multierr.Errors(err) {
+		var portErr *serial.PortError
+		require.ErrorAs(t, e, &portErr)
+		codes = append(codes, portErr.Code())
+	}
+	assert.Equal(t, []serial.PortErrorCode{
+		serial.InvalidDataBits,
+		serial.InvalidParity,
+		serial.InvalidTimeoutValue,
+		serial.InvalidTimeoutValue,
+	}, codes)
+	assert.Contains(t, err.Error(), "DataBits 9")
+	assert.Contains(t, err.Error(), "RS485.DelayRTSAfterSend -1")
+}
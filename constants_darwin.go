@@ -20,8 +20,25 @@ const (
 	tcCCTS_OFLOW uint64 = 0x00010000 //nolint:revive,stylecheck
 	tcCRTS_IFLOW uint64 = 0x00020000 //nolint:revive,stylecheck
 	tcCRTSCTS           = tcCCTS_OFLOW | tcCRTS_IFLOW
+
+	tcCDTR_IFLOW uint64 = 0x00040000 //nolint:revive,stylecheck
+	tcCDSR_OFLOW uint64 = 0x00080000 //nolint:revive,stylecheck
+	tcCDTRDSR           = tcCDTR_IFLOW | tcCDSR_OFLOW
 )
 
+// platformCapabilities describes the configuration supported by the platform, see Config.Validate().
+var platformCapabilities = capabilities{
+	baudRate:               nil, // any baud rate is set via IOSSIOSPEED
+	markSpaceParity:        false,
+	onePointFiveStopBits:   false,
+	dtrDSRFlowControl:      true,
+	softwareFlowControlAny: true,
+	rs485WithRTSCTS:        false, // RS-485 is emulated by driving RTS
+	lineErrorReporting:     true,
+	exclusiveModes:         true,
+	uucpLock:               true,
+}
+
 var databitsMap = map[int]uint64{
 	0: unix.CS8, // Default to 8 bits
 	5: unix.CS5,
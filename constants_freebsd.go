@@ -19,9 +19,26 @@ const (
 	// tcCRTS_IFLOW uint32 = 0x00020000 //nolint:revive,stylecheck
 	tcCRTSCTS = tcCCTS_OFLOW
 
+	tcCDTR_IFLOW uint32 = 0x00040000 //nolint:revive,stylecheck
+	tcCDSR_OFLOW uint32 = 0x00080000 //nolint:revive,stylecheck
+	tcCDTRDSR           = tcCDTR_IFLOW | tcCDSR_OFLOW
+
 	ioctlTcflsh = unix.TIOCFLUSH
 )
 
+// platformCapabilities describes the configuration supported by the platform, see Config.Validate().
+var platformCapabilities = capabilities{
+	baudRate:               isStandardBaudrate,
+	markSpaceParity:        false,
+	onePointFiveStopBits:   false,
+	dtrDSRFlowControl:      true,
+	softwareFlowControlAny: true,
+	rs485WithRTSCTS:        false, // RS-485 is emulated by driving RTS
+	lineErrorReporting:     true,
+	exclusiveModes:         true,
+	uucpLock:               true,
+}
+
 var (
 	baudrateMap = map[int]uint32{
 		0:      unix.B9600, // Default to 9600
@@ -10,15 +10,28 @@ import "golang.org/x/sys/unix"
 
 const (
 	devicesBasePath = "/dev"
-	regexFilter     = "(ttyS|ttyUSB|ttyACM|ttyAMA|rfcomm|ttyO)[0-9]{1,3}"
 
 	tcCMSPAR         = unix.CMSPAR
 	tcIUCLC          = unix.IUCLC
 	tcCRTSCTS uint32 = unix.CRTSCTS
+	tcCDTRDSR uint32 = 0 // not supported
 
 	ioctlTcflsh = unix.TCFLSH
 )
 
+// platformCapabilities describes the configuration supported by the platform, see Config.Validate().
+var platformCapabilities = capabilities{
+	baudRate:               nil, // any baud rate is set via BOTHER
+	markSpaceParity:        true,
+	onePointFiveStopBits:   false,
+	dtrDSRFlowControl:      false,
+	softwareFlowControlAny: true,
+	rs485WithRTSCTS:        true,
+	lineErrorReporting:     true,
+	exclusiveModes:         true,
+	uucpLock:               true,
+}
+
 var (
 	baudrateMap = map[int]uint32{
 		0:       unix.B9600, // Default to 9600
@@ -18,10 +18,24 @@ const (
 	tcCCTS_OFLOW uint32 = 0x00010000 //nolint:revive,stylecheck
 	// tcCRTS_IFLOW uint32 = 0x00020000 //nolint:revive,stylecheck
 	tcCRTSCTS = tcCCTS_OFLOW
+	tcCDTRDSR = 0 // not supported
 
 	ioctlTcflsh = unix.TIOCFLUSH
 )
 
+// platformCapabilities describes the configuration supported by the platform, see Config.Validate().
+var platformCapabilities = capabilities{
+	baudRate:               isStandardBaudrate,
+	markSpaceParity:        false,
+	onePointFiveStopBits:   false,
+	dtrDSRFlowControl:      false,
+	softwareFlowControlAny: true,
+	rs485WithRTSCTS:        false, // RS-485 is emulated by driving RTS
+	lineErrorReporting:     true,
+	exclusiveModes:         true,
+	uucpLock:               true,
+}
+
 var baudrateMap = map[int]uint32{
 	0:      unix.B9600, // Default to 9600
 	50:     unix.B50,
@@ -0,0 +1,59 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+// Counters contains the serial line interrupt and error counters, see Port.Counters().
+// Counters may wrap around, use Delta() to get the difference between two snapshots.
+type Counters struct {
+	RX            uint32 // Characters received
+	TX            uint32 // Characters transmitted
+	Frame         uint32 // Framing errors
+	Overrun       uint32 // Hardware (UART) overrun errors
+	Parity        uint32 // Parity errors
+	Break         uint32 // Break conditions received
+	BufferOverrun uint32 // Input buffer overrun errors
+	CTS           uint32 // ClearToSend line transitions
+	DSR           uint32 // DataSetReady line transitions
+	RI            uint32 // RingIndicator line transitions
+	DCD           uint32 // DataCarrierDetect line transitions
+}
+
+// Delta returns the counters increase since the prev snapshot.
+func (c Counters) Delta(prev Counters) Counters {
+	return Counters{
+		RX:            c.RX - prev.RX,
+		TX:            c.TX - prev.TX,
+		Frame:         c.Frame - prev.Frame,
+		Overrun:       c.Overrun - prev.Overrun,
+		Parity:        c.Parity - prev.Parity,
+		Break:         c.Break - prev.Break,
+		BufferOverrun: c.BufferOverrun - prev.BufferOverrun,
+		CTS:           c.CTS - prev.CTS,
+		DSR:           c.DSR - prev.DSR,
+		RI:            c.RI - prev.RI,
+		DCD:           c.DCD - prev.DCD,
+	}
+}
+
+// modemLines returns the modem status lines with non-zero transition counters.
+func (c Counters) modemLines() ModemStatusMask {
+	var m ModemStatusMask
+	if c.CTS != 0 {
+		m |= ModemCTS
+	}
+	if c.DSR != 0 {
+		m |= ModemDSR
+	}
+	if c.RI != 0 {
+		m |= ModemRI
+	}
+	if c.DCD != 0 {
+		m |= ModemDCD
+	}
+	return m
+}
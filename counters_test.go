@@ -0,0 +1,17 @@
+package serial_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/albenik/go-serial/v2"
+)
+
+func TestCounters_Delta(t *testing.T) {
+	prev := serial.Counters{RX: 10, TX: math.MaxUint32 - 1, Overrun: 1}
+	cur := serial.Counters{RX: 25, TX: 3, Overrun: 1, Frame: 2}
+
+	assert.Equal(t, serial.Counters{RX: 15, TX: 5, Frame: 2}, cur.Delta(prev))
+}
@@ -0,0 +1,43 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// deadline is an absolute I/O deadline which may be changed while an operation is pending.
+type deadline struct {
+	t atomic.Int64 // Unix time in nanoseconds, zero means no deadline
+}
+
+func (d *deadline) set(t time.Time) {
+	if t.IsZero() {
+		d.t.Store(0)
+		return
+	}
+	d.t.Store(t.UnixNano())
+}
+
+func (d *deadline) get() time.Time {
+	if t := d.t.Load(); t != 0 {
+		return time.Unix(0, t)
+	}
+	return time.Time{}
+}
+
+func (d *deadline) expired() bool {
+	t := d.get()
+	return !t.IsZero() && !time.Now().Before(t)
+}
+
+func newDeadlineExceededError() *PortError {
+	return &PortError{code: DeadlineExceeded, wrapped: os.ErrDeadlineExceeded}
+}
@@ -52,6 +52,18 @@ The configuration can be changed at any time with the Reconfigure() function:
 		log.Fatal(err)
 	}
 
+The whole configuration may be described with the Config struct as well, e.g. loaded from
+a JSON, YAML or TOML file, and applied with the OpenWithConfig() or ApplyConfig() functions:
+
+	var cfg serial.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Fatal(err)
+	}
+	port, err := serial.OpenWithConfig("/dev/ttyUSB0", cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 The port object implements the io.ReadWriteCloser interface, so we can use
 the usual Read, Write and Close functions to send and receive data from the
 serial port:
@@ -79,11 +91,10 @@ serial port:
 If a port is a virtual USB-CDC serial port (for example an USB-to-RS232
 cable or a microcontroller development board) is possible to retrieve
 the USB metadata, like VID/PID or USB Serial Number, with the
-GetDetailedPortsList function in the enumerator package:
-
-	import "github.com/albenik/go-serial/v2/enumerator"
+GetDetailedPortsList function (linux only, only the port names are
+returned on the other platforms):
 
-	ports, err := enumerator.GetDetailedPortsList()
+	ports, err := serial.GetDetailedPortsList()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -99,13 +110,42 @@ GetDetailedPortsList function in the enumerator package:
 		}
 	}
 
-for details on USB port enumeration see the documentation of the specific package.
+The details are read from sysfs, no device is opened.
+
+The USB adapter may be opened by its identity instead of the device path,
+which changes when the adapters are plugged in a different order:
+
+	port, err := serial.OpenMatching(serial.PortFilter{VID: "0403", SerialNumber: "A1B2C3"})
+
+ErrPortNotFound is returned if no port matches the filter, ErrPortAmbiguous
+wrapping AmbiguousMatch with the candidates if more than one port does.
+
+The ports arrival and removal may be watched with the Watch function (linux
+kernel uevents are used, the ports list is polled on the other platforms):
+
+	events, err := serial.Watch(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for ev := range events {
+		fmt.Printf("Port %s %s\n", ev.Port.Name, ev.Type)
+	}
+
+Long-running applications may use ReconnectingPort, which reopens the port
+with the same options after the device is unplugged and plugged in again:
+
+	port, err := serial.OpenReconnectingMatching(
+		serial.PortFilter{VID: "0403", SerialNumber: "A1B2C3"},
+		serial.ReconnectOptions{OnStateChange: func(s serial.ConnState, err error) {
+			log.Printf("port %s: %v", s, err)
+		}},
+		serial.WithBaudrate(115200))
+
+Read and Write block until the port is reopened unless
+ReconnectOptions.FailFast is set.
 
 This library tries to avoid the use of the "C" package (and consequently the need
 of cgo) to simplify cross compiling.
-Unfortunately the USB enumeration package for darwin (MacOSX) requires cgo
-to access the IOKit framework. This means that if you need USB enumeration
-on darwin you're forced to use cgo.
 */
 
 package serial
@@ -0,0 +1,72 @@
+//go:build linux && !android
+
+package serial
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutQueue replaces the output queue size ioctl, the pseudo terminal transmits everything at once.
+func fakeOutQueue(t *testing.T, n int32) *atomic.Int32 {
+	t.Helper()
+
+	q := new(atomic.Int32)
+	q.Store(n)
+	prev := ioctlOutQueue
+	t.Cleanup(func() { ioctlOutQueue = prev })
+	ioctlOutQueue = func(int) (int, error) { return int(q.Load()), nil }
+	return q
+}
+
+func TestPort_Drain(t *testing.T) {
+	q := fakeOutQueue(t, 16)
+	_, name := openPTY(t)
+	p, err := Open(name)
+	require.NoError(t, err)
+	defer p.Close()
+
+	time.AfterFunc(3*drainPollInterval, func() { q.Store(0) })
+	require.NoError(t, p.Drain())
+
+	q.Store(16)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*drainPollInterval)
+	defer cancel()
+	err = p.DrainContext(ctx)
+	require.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, p.SetWriteTimeout(30))
+	assert.ErrorIs(t, p.Drain(), ErrTimeout)
+}
+
+func TestPort_Drain_Close(t *testing.T) {
+	fakeOutQueue(t, 16)
+	_, name := openPTY(t)
+	fds := openFDs(t)
+
+	p, err := Open(name, WithExclusive(ExclusiveFlock))
+	require.NoError(t, err)
+
+	res := make(chan error, 1)
+	go func() { res <- p.Drain() }()
+	time.Sleep(3 * drainPollInterval)
+	require.NoError(t, p.Close())
+	select {
+	case err := <-res:
+		assert.ErrorIs(t, err, ErrClosed)
+	case <-time.After(time.Second):
+		require.FailNow(t, "drain is not interrupted by Close")
+	}
+
+	// Nothing keeps the device open after Close
+	assert.Equal(t, fds, openFDs(t))
+	p, err = Open(name, WithExclusive(ExclusiveFlock))
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+}
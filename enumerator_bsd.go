@@ -0,0 +1,43 @@
+//
+// Copyright 2014-2018 Cristian Maglie. All rights reserved.
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+//go:build darwin || freebsd || openbsd
+
+package serial
+
+import (
+	"os"
+	"path"
+	"regexp"
+)
+
+var portNameRx = regexp.MustCompile(regexFilter)
+
+// GetPortsList returns the names of the devices matching the serial ports naming convention.
+func GetPortsList() ([]string, error) {
+	files, err := os.ReadDir(devicesBasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]string, 0, len(files))
+	for _, f := range files {
+		// Skip folders
+		if f.IsDir() {
+			continue
+		}
+
+		// Keep only devices with the correct name
+		if !portNameRx.MatchString(f.Name()) {
+			continue
+		}
+
+		ports = append(ports, path.Join(devicesBasePath, f.Name()))
+	}
+
+	return ports, nil
+}
@@ -0,0 +1,227 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sysfsEnumerator enumerates the serial ports using sysfs, the roots are configurable for testing.
+type sysfsEnumerator struct {
+	sysRoot string
+	devRoot string
+}
+
+var defaultEnumerator = sysfsEnumerator{sysRoot: "/sys", devRoot: devicesBasePath}
+
+// GetPortsList returns the names of the ttys backed by a serial driver, no device is opened.
+// See GetDetailedPortsList() for details.
+func GetPortsList() ([]string, error) {
+	ports, err := defaultEnumerator.ports()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(ports))
+	for i, p := range ports {
+		names[i] = p.Name
+	}
+	return names, nil
+}
+
+// GetDetailedPortsList returns the ttys backed by a serial driver (not the virtual terminals and not the unused
+// serial8250 placeholders) with the details read from sysfs: persistent symlinks, kernel driver, bus type
+// and USB identity. Any tty name is supported (ttyS, ttyUSB, ttyACM, ttyXRUSB, ttyTHS, ttyMXC, ...).
+// The virtual serial ports with no device, USB gadget serial (ttyGS) and Bluetooth RFCOMM (rfcomm), are listed
+// with the empty bus type. No device is opened.
+func GetDetailedPortsList() ([]*PortDetails, error) {
+	return defaultEnumerator.ports()
+}
+
+// resolved returns the enumerator with sysRoot symlinks resolved, as the sysfs links are resolved by port().
+func (e sysfsEnumerator) resolved() sysfsEnumerator {
+	if root, err := filepath.EvalSymlinks(e.sysRoot); err == nil {
+		e.sysRoot = root
+	}
+	return e
+}
+
+func (e sysfsEnumerator) ports() ([]*PortDetails, error) {
+	e = e.resolved()
+	entries, err := os.ReadDir(filepath.Join(e.sysRoot, "class", "tty"))
+	if err != nil {
+		return nil, &PortError{code: ErrorEnumeratingPorts, wrapped: err}
+	}
+
+	links := e.serialLinks()
+	ports := make([]*PortDetails, 0, len(entries))
+	for _, entry := range entries {
+		if d := e.port(entry.Name(), links); d != nil {
+			ports = append(ports, d)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Name < ports[j].Name })
+	return ports, nil
+}
+
+// virtualSerialDrivers are the tty drivers of the serial ports which may have no device or no device driver,
+// by the tty name prefix.
+var virtualSerialDrivers = []struct{ prefix, driver string }{
+	{prefix: "ttyGS", driver: "g_serial"}, // USB gadget serial, the device side of the USB link
+	{prefix: "rfcomm", driver: "rfcomm"},  // Bluetooth RFCOMM, bound to the HCI connection only when connected
+}
+
+// virtualSerialDriver returns the driver of the virtual serial tty, empty string for other ttys.
+func virtualSerialDriver(tty string) string {
+	for _, v := range virtualSerialDrivers {
+		if strings.HasPrefix(tty, v.prefix) {
+			return v.driver
+		}
+	}
+	return ""
+}
+
+// port returns the details of the tty, nil if it is not backed by a serial driver.
+func (e sysfsEnumerator) port(tty string, links map[string]*PortDetails) *PortDetails {
+	classDir := filepath.Join(e.sysRoot, "class", "tty", tty)
+	virtual := virtualSerialDriver(tty)
+	dev, err := filepath.EvalSymlinks(filepath.Join(classDir, "device"))
+	if err != nil && (virtual == "" || !fileExists(filepath.Join(classDir, "dev"))) {
+		return nil // virtual terminal
+	}
+	// Serial core reports unknown UART type (PORT_UNKNOWN) for the ports with no UART detected,
+	// e.g. serial8250 registers ttyS0-ttyS31 regardless of the hardware present.
+	if readAttr(classDir, "type") == "0" {
+		return nil
+	}
+
+	name := filepath.Join(e.devRoot, tty)
+	if n := readUevent(classDir)["DEVNAME"]; n != "" {
+		name = filepath.Join(e.devRoot, n)
+	}
+	d := &PortDetails{Name: name}
+	if l := links[name]; l != nil {
+		d.IDs, d.Paths = l.IDs, l.Paths
+	}
+	if err != nil {
+		d.Driver = virtual // no device, e.g. ttyGS with no UDC bound
+		return d
+	}
+
+	// Skip the serial core port devices (linux 6.1+) up to the device of the real driver
+	for linkName(dev, "subsystem") == "serial-base" {
+		dev = filepath.Dir(dev)
+	}
+	if d.Driver = linkName(dev, "driver"); d.Driver == "" {
+		if virtual == "" {
+			return nil // no driver bound
+		}
+		d.Driver = virtual
+	}
+	d.Bus = linkName(dev, "subsystem")
+
+	// USB serial converter port (e.g. ftdi_sio), USB interface (e.g. cdc_acm) and USB device are nested
+	for dir := dev; strings.HasPrefix(dir, e.sysRoot) && dir != e.sysRoot; dir = filepath.Dir(dir) {
+		if !d.IsUSB && fileExists(filepath.Join(dir, "bInterfaceNumber")) {
+			d.IsUSB = true
+			d.Bus = "usb"
+			if n, err := strconv.ParseInt(readAttr(dir, "bInterfaceNumber"), 16, 0); err == nil {
+				d.Interface = int(n)
+			}
+		}
+		if fileExists(filepath.Join(dir, "idVendor")) {
+			d.IsUSB = true
+			d.Bus = "usb"
+			d.VID = readAttr(dir, "idVendor")
+			d.PID = readAttr(dir, "idProduct")
+			d.Manufacturer = readAttr(dir, "manufacturer")
+			d.Product = readAttr(dir, "product")
+			d.SerialNumber = readAttr(dir, "serial")
+			d.USBPath = filepath.Base(dir)
+			break
+		}
+	}
+	return d
+}
+
+// serialLinks returns the persistent symlinks of the devices from /dev/serial/by-id and /dev/serial/by-path.
+func (e sysfsEnumerator) serialLinks() map[string]*PortDetails {
+	links := make(map[string]*PortDetails)
+	collect := func(dir string, add func(d *PortDetails, link string)) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return // no such directory if there are no serial devices
+		}
+		for _, entry := range entries {
+			link := filepath.Join(dir, entry.Name())
+			target, err := os.Readlink(link)
+			if err != nil {
+				continue
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir, target)
+			}
+			target = filepath.Clean(target)
+			d := links[target]
+			if d == nil {
+				d = &PortDetails{}
+				links[target] = d
+			}
+			add(d, link)
+		}
+	}
+	collect(filepath.Join(e.devRoot, "serial", "by-id"), func(d *PortDetails, link string) { d.IDs = append(d.IDs, link) })
+	collect(filepath.Join(e.devRoot, "serial", "by-path"), func(d *PortDetails, link string) { d.Paths = append(d.Paths, link) })
+	return links
+}
+
+// readAttr returns the trimmed content of the sysfs attribute, empty string if not available.
+func readAttr(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return string(bytes.TrimSpace(b))
+}
+
+// readUevent returns the key-value pairs of the sysfs uevent attribute.
+func readUevent(dir string) map[string]string {
+	vars := make(map[string]string)
+	f, err := os.Open(filepath.Join(dir, "uevent"))
+	if err != nil {
+		return vars
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if k, v, ok := strings.Cut(s.Text(), "="); ok {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// linkName returns the base name of the symlink target, e.g. the driver name for the "driver" link.
+func linkName(dir, name string) string {
+	target, err := os.Readlink(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,259 @@
+package serial
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// makeTree creates the files in the root, values prefixed with "->" are symlinks targets.
+func makeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		name = filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+		if strings.HasPrefix(content, "->") {
+			require.NoError(t, os.Symlink(strings.TrimPrefix(content, "->"), name))
+			continue
+		}
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	}
+}
+
+const (
+	fixtureUSB  = "devices/pci0000:00/0000:00:14.0/usb1"
+	fixtureFTDI = fixtureUSB + "/1-2/1-2:1.0/ttyUSB0"
+	fixtureACM  = fixtureUSB + "/1-3/1-3:1.0"
+	fixturePNP  = "devices/pnp0/00:01"
+	fixtureS0   = fixturePNP + "/00:01:0/00:01:0.0"
+	fixtureS1   = "devices/platform/serial8250"
+	fixtureTHS  = "devices/platform/bus@0/3100000.serial"
+	fixtureX    = "devices/platform/unbound"
+	fixtureTTY  = "devices/virtual/tty/tty0"
+	fixtureGS   = "devices/virtual/tty/ttyGS0"
+	fixtureRF0  = "devices/virtual/tty/rfcomm0"
+	fixtureHCI  = fixtureUSB + "/1-4/1-4:1.0/bluetooth/hci0/hci0:12"
+)
+
+// sysfsFixture returns the enumerator of the fixture tree with FTDI and CDC ACM USB adapters,
+// on-board UARTs, 8250 placeholder port, the device with no driver bound, virtual terminal, USB gadget serial
+// and Bluetooth RFCOMM ports.
+func sysfsFixture(t *testing.T) sysfsEnumerator {
+	t.Helper()
+
+	root := t.TempDir()
+	makeTree(t, filepath.Join(root, "sys"), map[string]string{
+		fixtureUSB + "/1-2/idVendor":                 "0403\n",
+		fixtureUSB + "/1-2/idProduct":                "6001\n",
+		fixtureUSB + "/1-2/manufacturer":             "FTDI\n",
+		fixtureUSB + "/1-2/product":                  "FT232R USB UART\n",
+		fixtureUSB + "/1-2/serial":                   "A1B2C3\n",
+		fixtureUSB + "/1-2/1-2:1.0/bInterfaceNumber": "00\n",
+		fixtureFTDI + "/driver":                      "->../../../../../../../bus/usb-serial/drivers/ftdi_sio",
+		fixtureFTDI + "/subsystem":                   "->../../../../../../../bus/usb-serial",
+		fixtureFTDI + "/tty/ttyUSB0/uevent":          "MAJOR=188\nMINOR=0\nDEVNAME=ttyUSB0\n",
+		fixtureFTDI + "/tty/ttyUSB0/device":          "->../../../ttyUSB0",
+
+		fixtureUSB + "/1-3/idVendor":       "2341\n",
+		fixtureUSB + "/1-3/idProduct":      "0043\n",
+		fixtureUSB + "/1-3/product":        "Arduino Uno\n",
+		fixtureUSB + "/1-3/serial":         "75833353035351A0E1B2\n",
+		fixtureACM + "/bInterfaceNumber":   "01\n",
+		fixtureACM + "/driver":             "->../../../../../../bus/usb/drivers/cdc_acm",
+		fixtureACM + "/subsystem":          "->../../../../../../bus/usb",
+		fixtureACM + "/tty/ttyACM0/uevent": "MAJOR=166\nMINOR=0\nDEVNAME=ttyACM0\n",
+		fixtureACM + "/tty/ttyACM0/device": "->../../../1-3:1.0",
+		fixturePNP + "/driver":             "->../../../bus/pnp/drivers/serial",
+		fixturePNP + "/subsystem":          "->../../../bus/pnp",
+		fixturePNP + "/00:01:0/driver":     "->../../../../bus/serial-base/drivers/ctrl",
+		fixturePNP + "/00:01:0/subsystem":  "->../../../../bus/serial-base",
+		fixtureS0 + "/driver":              "->../../../../../bus/serial-base/drivers/port",
+		fixtureS0 + "/subsystem":           "->../../../../../bus/serial-base",
+		fixtureS0 + "/tty/ttyS0/uevent":    "MAJOR=4\nMINOR=64\nDEVNAME=ttyS0\n",
+		fixtureS0 + "/tty/ttyS0/type":      "4\n",
+		fixtureS0 + "/tty/ttyS0/port":      "0x3F8\n",
+		fixtureS0 + "/tty/ttyS0/device":    "->../../../00:01:0.0",
+		fixtureS1 + "/driver":              "->../../../bus/platform/drivers/serial8250",
+		fixtureS1 + "/subsystem":           "->../../../bus/platform",
+		fixtureS1 + "/tty/ttyS1/uevent":    "MAJOR=4\nMINOR=65\nDEVNAME=ttyS1\n",
+		fixtureS1 + "/tty/ttyS1/type":      "0\n",
+		fixtureS1 + "/tty/ttyS1/port":      "0x0\n",
+		fixtureS1 + "/tty/ttyS1/device":    "->../../../serial8250",
+		fixtureS1 + "/tty/ttyS2/uevent":    "MAJOR=4\nMINOR=66\nDEVNAME=ttyS2\n",
+		fixtureS1 + "/tty/ttyS2/type":      "4\n",
+		fixtureS1 + "/tty/ttyS2/port":      "0x2F8\n",
+		fixtureS1 + "/tty/ttyS2/device":    "->../../../serial8250",
+		fixtureTHS + "/driver":             "->../../../../bus/platform/drivers/tegra-hsuart",
+		fixtureTHS + "/subsystem":          "->../../../../bus/platform",
+		fixtureTHS + "/tty/ttyTHS1/uevent": "MAJOR=238\nMINOR=1\nDEVNAME=ttyTHS1\n",
+		fixtureTHS + "/tty/ttyTHS1/device": "->../../../3100000.serial",
+		fixtureX + "/subsystem":            "->../../../bus/platform",
+		fixtureX + "/tty/ttyX0/uevent":     "MAJOR=240\nMINOR=0\nDEVNAME=ttyX0\n",
+		fixtureX + "/tty/ttyX0/device":     "->../../../unbound",
+		fixtureTTY + "/uevent":             "MAJOR=4\nMINOR=0\nDEVNAME=tty0\n",
+		fixtureTTY + "/dev":                "4:0\n",
+		fixtureGS + "/uevent":              "MAJOR=244\nMINOR=0\nDEVNAME=ttyGS0\n",
+		fixtureGS + "/dev":                 "244:0\n",
+		fixtureRF0 + "/uevent":             "MAJOR=216\nMINOR=0\nDEVNAME=rfcomm0\n",
+		fixtureRF0 + "/dev":                "216:0\n",
+		fixtureHCI + "/subsystem":          "->../../../../../../../../../../bus/bluetooth",
+		fixtureHCI + "/rfcomm1/uevent":     "MAJOR=216\nMINOR=1\nDEVNAME=rfcomm1\n",
+		fixtureHCI + "/rfcomm1/dev":        "216:1\n",
+		fixtureHCI + "/rfcomm1/device":     "->../../hci0:12",
+		"class/tty/ttyUSB0":                "->../../" + fixtureFTDI + "/tty/ttyUSB0",
+		"class/tty/ttyACM0":                "->../../" + fixtureACM + "/tty/ttyACM0",
+		"class/tty/ttyS0":                  "->../../" + fixtureS0 + "/tty/ttyS0",
+		"class/tty/ttyS1":                  "->../../" + fixtureS1 + "/tty/ttyS1",
+		"class/tty/ttyS2":                  "->../../" + fixtureS1 + "/tty/ttyS2",
+		"class/tty/ttyTHS1":                "->../../" + fixtureTHS + "/tty/ttyTHS1",
+		"class/tty/ttyX0":                  "->../../" + fixtureX + "/tty/ttyX0",
+		"class/tty/tty0":                   "->../../" + fixtureTTY,
+		"class/tty/ttyGS0":                 "->../../" + fixtureGS,
+		"class/tty/rfcomm0":                "->../../" + fixtureRF0,
+		"class/tty/rfcomm1":                "->../../" + fixtureHCI + "/rfcomm1",
+	})
+	makeTree(t, filepath.Join(root, "dev"), map[string]string{
+		"ttyUSB0": "",
+		"ttyACM0": "",
+		"ttyS0":   "",
+		"ttyS1":   "",
+		"ttyS2":   "",
+		"ttyTHS1": "",
+		"ttyX0":   "",
+		"tty0":    "",
+		"ttyGS0":  "",
+		"rfcomm0": "",
+		"rfcomm1": "",
+		"serial/by-id/usb-FTDI_FT232R_USB_UART_A1B2C3-if00-port0":         "->../../ttyUSB0",
+		"serial/by-id/usb-Arduino_Uno_75833353035351A0E1B2-if01":          "->../../ttyACM0",
+		"serial/by-path/pci-0000:00:14.0-usb-0:2:1.0-port0":               "->../../ttyUSB0",
+		"serial/by-path/pci-0000:00:14.0-usbv2-0:2:1.0-port0":             "->../../ttyUSB0",
+		"serial/by-path/pci-0000:00:14.0-usb-0:3:1.1":                     "->../../ttyACM0",
+		"serial/by-path/pci-0000:00:14.0-usb-0:9:1.0-port0-disconnected0": "->../../ttyUSB9",
+	})
+
+	return sysfsEnumerator{sysRoot: filepath.Join(root, "sys"), devRoot: filepath.Join(root, "dev")}
+}
+
+func TestSysfsEnumerator_Ports(t *testing.T) {
+	e := sysfsFixture(t)
+	dev := func(name string) string { return filepath.Join(e.devRoot, name) }
+
+	ports, err := e.ports()
+	require.NoError(t, err)
+
+	want := []*PortDetails{
+		{Name: dev("rfcomm0"), Driver: "rfcomm"},
+		{Name: dev("rfcomm1"), Driver: "rfcomm", Bus: "bluetooth"},
+		{
+			Name:         dev("ttyACM0"),
+			IDs:          []string{dev("serial/by-id/usb-Arduino_Uno_75833353035351A0E1B2-if01")},
+			Paths:        []string{dev("serial/by-path/pci-0000:00:14.0-usb-0:3:1.1")},
+			Driver:       "cdc_acm",
+			Bus:          "usb",
+			IsUSB:        true,
+			VID:          "2341",
+			PID:          "0043",
+			Product:      "Arduino Uno",
+			SerialNumber: "75833353035351A0E1B2",
+			Interface:    1,
+			USBPath:      "1-3",
+		},
+		{Name: dev("ttyGS0"), Driver: "g_serial"},
+		{Name: dev("ttyS0"), Driver: "serial", Bus: "pnp"},
+		{Name: dev("ttyS2"), Driver: "serial8250", Bus: "platform"},
+		{Name: dev("ttyTHS1"), Driver: "tegra-hsuart", Bus: "platform"},
+		{
+			Name: dev("ttyUSB0"),
+			IDs:  []string{dev("serial/by-id/usb-FTDI_FT232R_USB_UART_A1B2C3-if00-port0")},
+			Paths: []string{
+				dev("serial/by-path/pci-0000:00:14.0-usb-0:2:1.0-port0"),
+				dev("serial/by-path/pci-0000:00:14.0-usbv2-0:2:1.0-port0"),
+			},
+			Driver:       "ftdi_sio",
+			Bus:          "usb",
+			IsUSB:        true,
+			VID:          "0403",
+			PID:          "6001",
+			Manufacturer: "FTDI",
+			Product:      "FT232R USB UART",
+			SerialNumber: "A1B2C3",
+			Interface:    0,
+			USBPath:      "1-2",
+		},
+	}
+	assert.Equal(t, want, ports)
+}
+
+func TestSysfsEnumerator_NoSysfs(t *testing.T) {
+	e := sysfsEnumerator{sysRoot: filepath.Join(t.TempDir(), "sys"), devRoot: "/dev"}
+
+	_, err := e.ports()
+	var portErr *PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, ErrorEnumeratingPorts, portErr.Code())
+}
+
+func TestGetPortsList_Linux(t *testing.T) {
+	e := sysfsFixture(t)
+	defer func(prev sysfsEnumerator) { defaultEnumerator = prev }(defaultEnumerator)
+	defaultEnumerator = e
+
+	ports, err := GetPortsList()
+	require.NoError(t, err)
+	var names []string
+	for _, p := range ports {
+		names = append(names, filepath.Base(p))
+	}
+	assert.Equal(t, []string{"rfcomm0", "rfcomm1", "ttyACM0", "ttyGS0", "ttyS0", "ttyS2", "ttyTHS1", "ttyUSB0"}, names)
+}
+
+func TestOpenMatching(t *testing.T) {
+	e := sysfsFixture(t)
+	defer func(prev sysfsEnumerator) { defaultEnumerator = prev }(defaultEnumerator)
+	defaultEnumerator = e
+
+	// The fixture FTDI adapter device is backed by a pseudo-terminal
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer m.Close()
+	require.NoError(t, unix.IoctlSetPointerInt(int(m.Fd()), unix.TIOCSPTLCK, 0))
+	n, err := unix.IoctlGetInt(int(m.Fd()), unix.TIOCGPTN)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(e.devRoot, "ttyUSB0")))
+	require.NoError(t, os.Symlink(fmt.Sprintf("/dev/pts/%d", n), filepath.Join(e.devRoot, "ttyUSB0")))
+
+	p, err := OpenMatching(PortFilter{VID: "0403", SerialNumber: "A1B2C3"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(e.devRoot, "ttyUSB0"), p.String())
+	require.NoError(t, p.Close())
+
+	zero := 0
+	p, err = OpenMatching(PortFilter{Product: "FT232R*", Interface: &zero, USBPath: "1-2"})
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+
+	_, err = OpenMatching(PortFilter{VID: "10c4"})
+	require.ErrorIs(t, err, ErrPortNotFound)
+	assert.EqualError(t, err, "open vid=10c4: serial port not found")
+
+	_, err = OpenMatching(PortFilter{Product: "*"})
+	require.ErrorIs(t, err, ErrPortAmbiguous)
+	var ambiguous *AmbiguousMatch
+	require.ErrorAs(t, err, &ambiguous)
+	require.Len(t, ambiguous.Candidates, 2)
+	assert.Equal(t, filepath.Join(e.devRoot, "ttyACM0"), ambiguous.Candidates[0].Name)
+	assert.Equal(t, filepath.Join(e.devRoot, "ttyUSB0"), ambiguous.Candidates[1].Name)
+	assert.EqualError(t, err, fmt.Sprintf(`open product="*": ambiguous match: candidates: `+
+		"%s (2341:0043 75833353035351A0E1B2, usb 1-3, interface 1), %s (0403:6001 A1B2C3, usb 1-2, interface 0)",
+		ambiguous.Candidates[0].Name, ambiguous.Candidates[1].Name))
+}
@@ -0,0 +1,24 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+//go:build !linux
+
+package serial
+
+// GetDetailedPortsList returns the serial ports found by GetPortsList(), the details are not available
+// on this platform, only PortDetails.Name is filled.
+func GetDetailedPortsList() ([]*PortDetails, error) {
+	names, err := GetPortsList()
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]*PortDetails, len(names))
+	for i, name := range names {
+		ports[i] = &PortDetails{Name: name}
+	}
+	return ports, nil
+}
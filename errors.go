@@ -35,12 +35,51 @@ const (
 	WriteFailed
 	// ReadFailed Port read failed.
 	ReadFailed
+	// OperationCanceled the operation was canceled by its context.
+	OperationCanceled
+	// DeadlineExceeded the read or write deadline has been exceeded.
+	DeadlineExceeded
+	// InvalidFlowControl the selected flow control is not valid or not supported.
+	InvalidFlowControl
+	// InvalidMode the mode string could not be parsed.
+	InvalidMode
+	// PortDisconnected the device is gone (e.g. USB adapter unplugged) or the line is hung up.
+	PortDisconnected
+	// PortAmbiguous more than one port matches the filter passed to OpenMatching().
+	PortAmbiguous
+)
+
+// Sentinel errors matching any PortError with the corresponding code, e.g. errors.Is(err, serial.ErrPortBusy).
+var (
+	ErrPortBusy            error = &PortError{code: PortBusy}
+	ErrPortNotFound        error = &PortError{code: PortNotFound}
+	ErrInvalidSerialPort   error = &PortError{code: InvalidSerialPort}
+	ErrPermissionDenied    error = &PortError{code: PermissionDenied}
+	ErrInvalidSpeed        error = &PortError{code: InvalidSpeed}
+	ErrInvalidDataBits     error = &PortError{code: InvalidDataBits}
+	ErrInvalidParity       error = &PortError{code: InvalidParity}
+	ErrInvalidStopBits     error = &PortError{code: InvalidStopBits}
+	ErrInvalidTimeoutValue error = &PortError{code: InvalidTimeoutValue}
+	ErrEnumeratingPorts    error = &PortError{code: ErrorEnumeratingPorts}
+	ErrClosed              error = &PortError{code: PortClosed}
+	ErrNotImplemented      error = &PortError{code: FunctionNotImplemented}
+	ErrOS                  error = &PortError{code: OsError}
+	ErrWriteFailed         error = &PortError{code: WriteFailed}
+	ErrReadFailed          error = &PortError{code: ReadFailed}
+	ErrCanceled            error = &PortError{code: OperationCanceled}
+	ErrTimeout             error = &PortError{code: DeadlineExceeded}
+	ErrInvalidFlowControl  error = &PortError{code: InvalidFlowControl}
+	ErrInvalidMode         error = &PortError{code: InvalidMode}
+	ErrDisconnected        error = &PortError{code: PortDisconnected}
+	ErrPortAmbiguous       error = &PortError{code: PortAmbiguous}
 )
 
 // PortError is a platform independent error type for serial ports.
 type PortError struct {
 	code    PortErrorCode
 	wrapped error
+	op      string // Operation failed, e.g. "open" or "read" (may be empty)
+	port    string // Port name (may be empty)
 }
 
 // EncodedErrorString returns a string explaining the error code.
@@ -78,34 +117,110 @@ func (e PortError) EncodedErrorString() string {
 		return "read filed"
 	case WriteFailed:
 		return "write failed"
+	case OperationCanceled:
+		return "operation canceled"
+	case DeadlineExceeded:
+		return "i/o deadline exceeded"
+	case InvalidFlowControl:
+		return "port flow control invalid or not supported"
+	case InvalidMode:
+		return "port mode string invalid"
+	case PortDisconnected:
+		return "port disconnected"
+	case PortAmbiguous:
+		return "ambiguous match"
 	default:
 		return "other error"
 	}
 }
 
-// Error returns the complete error code with details on the cause of the error.
+// Error returns the complete error code with details on the cause of the error,
+// prefixed with the operation and the port name like *os.PathError does, e.g.
+// "open /dev/ttyUSB0: serial port busy".
 func (e PortError) Error() string {
+	msg := e.EncodedErrorString()
 	if e.wrapped != nil {
-		return e.EncodedErrorString() + ": " + e.wrapped.Error()
+		msg += ": " + e.wrapped.Error()
+	}
+	switch {
+	case e.op != "" && e.port != "":
+		return e.op + " " + e.port + ": " + msg
+	case e.op != "":
+		return e.op + ": " + msg
 	}
-	return e.EncodedErrorString()
+	return msg
 }
 
 func (e PortError) Unwrap() error {
 	return e.wrapped
 }
 
+// Is reports whether target is PortError with the same code, so the errors may be compared
+// with sentinels like ErrDisconnected using errors.Is().
+func (e PortError) Is(target error) bool {
+	t, ok := target.(*PortError)
+	return ok && t != nil && t.code == e.code
+}
+
+// Timeout reports whether the error is caused by an exceeded deadline (see net.Error).
+func (e PortError) Timeout() bool {
+	return e.code == DeadlineExceeded
+}
+
 // Code returns an identifier for the kind of error occurred.
 func (e PortError) Code() PortErrorCode {
 	return e.code
 }
 
+// Op returns the operation failed, e.g. "open" or "read", empty if unknown.
+func (e PortError) Op() string {
+	return e.op
+}
+
+// PortName returns the name of the port the error occurred on, empty if unknown.
+func (e PortError) PortName() string {
+	return e.port
+}
+
 // Cause returns the cause for the error
 // Deprecated: Use go1.13 error iterface Unwrap() instead.
 func (e PortError) Cause() error {
 	return e.Unwrap()
 }
 
+// withOp returns a copy of err with the operation op and the port name set, if err is PortError without them.
+// Other errors are returned as is.
+func withOp(err error, op, port string) error {
+	e, ok := err.(*PortError)
+	if !ok || e == nil || e.op != "" {
+		return err
+	}
+	c := *e
+	c.op, c.port = op, port
+	return &c
+}
+
+// wrapErr adds the operation op and the port name to *err, see withOp.
+func (p *Port) wrapErr(op string, err *error) {
+	if *err == nil {
+		return
+	}
+	var name string
+	if p != nil {
+		name = p.name
+	}
+	*err = withOp(*err, op, name)
+}
+
 func newPortOSError(err error) *PortError {
-	return &PortError{code: OsError, wrapped: err}
+	return wrapOSError(OsError, err)
+}
+
+// wrapOSError wraps the operating system error err into PortError with the code,
+// the errors caused by the disconnected device are reported with PortDisconnected code.
+func wrapOSError(code PortErrorCode, err error) *PortError {
+	if isDisconnectedError(err) {
+		return &PortError{code: PortDisconnected, wrapped: err}
+	}
+	return &PortError{code: code, wrapped: err}
 }
@@ -0,0 +1,60 @@
+package serial
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortError_Error(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: &PortError{code: PortBusy}, want: "serial port busy"},
+		{err: &PortError{code: PortNotFound, wrapped: os.ErrNotExist}, want: "serial port not found: file does not exist"},
+		{err: withOp(&PortError{code: PortClosed}, "read", ""), want: "read: port has been closed"},
+		{
+			err:  withOp(&PortError{code: PortNotFound, wrapped: os.ErrNotExist}, "open", "/dev/ttyUSB0"),
+			want: "open /dev/ttyUSB0: serial port not found: file does not exist",
+		},
+		{
+			// The innermost operation is kept
+			err:  withOp(withOp(&PortError{code: PortDisconnected}, "set rts", "COM1"), "write", "COM1"),
+			want: "set rts COM1: port disconnected",
+		},
+		{err: withOp(errors.New("other"), "open", "COM1"), want: "other"},
+	}
+
+	for _, tt := range tests {
+		assert.EqualError(t, tt.err, tt.want)
+	}
+}
+
+func TestPortError_Is(t *testing.T) {
+	sentinels := []error{
+		ErrPortBusy, ErrPortNotFound, ErrInvalidSerialPort, ErrPermissionDenied, ErrInvalidSpeed, ErrInvalidDataBits,
+		ErrInvalidParity, ErrInvalidStopBits, ErrInvalidTimeoutValue, ErrEnumeratingPorts, ErrClosed, ErrNotImplemented,
+		ErrOS, ErrWriteFailed, ErrReadFailed, ErrCanceled, ErrTimeout, ErrInvalidFlowControl, ErrInvalidMode,
+		ErrDisconnected, ErrPortAmbiguous,
+	}
+
+	codes := make(map[PortErrorCode]bool)
+	for _, s := range sentinels {
+		code := s.(*PortError).Code()
+		assert.False(t, codes[code], "duplicate sentinel for %v", s)
+		codes[code] = true
+
+		err := withOp(&PortError{code: code, wrapped: os.ErrInvalid}, "read", "COM1")
+		for _, other := range sentinels {
+			assert.Equal(t, s == other, errors.Is(err, other), "%v is %v", err, other)
+		}
+		assert.ErrorIs(t, err, os.ErrInvalid)
+	}
+	assert.Len(t, codes, int(PortAmbiguous)) // every code but PortErrorUnknown
+
+	assert.ErrorIs(t, newDeadlineExceededError(), os.ErrDeadlineExceeded)
+	assert.ErrorIs(t, newDeadlineExceededError(), ErrTimeout)
+}
@@ -0,0 +1,22 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// isDisconnectedError reports whether err means the device is gone (e.g. USB adapter unplugged).
+func isDisconnectedError(err error) bool {
+	return errors.Is(err, windows.ERROR_BAD_COMMAND) || // 22
+		errors.Is(err, windows.ERROR_GEN_FAILURE) || // 31
+		errors.Is(err, windows.ERROR_DEVICE_NOT_CONNECTED) || // 1167
+		errors.Is(err, windows.ERROR_DEVICE_REMOVED) // 1617
+}
@@ -7,6 +7,7 @@
 package serial_test
 
 import (
+	"errors"
 	"fmt"
 	"log"
 
@@ -26,3 +27,34 @@ func ExampleGetPortsList() {
 		}
 	}
 }
+
+func ExampleGetDetailedPortsList() {
+	ports, err := serial.GetDetailedPortsList()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, port := range ports {
+		fmt.Printf("Found port: %s\n", port.Name)
+		if port.IsUSB {
+			fmt.Printf("   USB ID     %s:%s\n", port.VID, port.PID)
+			fmt.Printf("   USB serial %s\n", port.SerialNumber)
+		}
+	}
+}
+
+func ExampleOpenMatching() {
+	port, err := serial.OpenMatching(serial.PortFilter{VID: "0403", PID: "6001", SerialNumber: "A1B2C3"},
+		serial.WithBaudrate(115200))
+	var ambiguous *serial.AmbiguousMatch
+	if errors.As(err, &ambiguous) {
+		for _, c := range ambiguous.Candidates {
+			fmt.Printf("Candidate: %s (usb %s)\n", c.Name, c.USBPath)
+		}
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer port.Close()
+	fmt.Printf("Opened %s\n", port)
+}
@@ -7,6 +7,7 @@
 package serial_test
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -70,3 +71,27 @@ func ExamplePort_GetModemStatusBits() {
 		}
 	}
 }
+
+func ExamplePort_WatchModemStatus() {
+	port, err := serial.Open("/dev/ttyACM1")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer port.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	events, err := port.WatchModemStatus(ctx, serial.ModemCTS|serial.ModemDCD)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for e := range events {
+		if e.Err != nil {
+			log.Println(e.Err)
+			break
+		}
+		fmt.Printf("%s: Status: %+v\n", e.Time.Format(time.RFC3339Nano), e.Status)
+	}
+}
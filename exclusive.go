@@ -0,0 +1,44 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import "fmt"
+
+// ExclusiveMode describes how the exclusive access to the port is acquired on open.
+// On windows the port is always opened exclusively, only the default mode is supported.
+type ExclusiveMode int
+
+const (
+	// ExclusiveTIOCEXCL sets TIOCEXCL on the device, so further opens fail with PortBusy
+	// (except for root, default, does nothing on android).
+	ExclusiveTIOCEXCL ExclusiveMode = iota
+	// ExclusiveNone does not acquire the exclusive access, e.g. for sniffing the port owned by another process.
+	ExclusiveNone
+	// ExclusiveFlock acquires the advisory flock(LOCK_EX|LOCK_NB) lock, respected by the cooperating processes only
+	// (including root ones).
+	ExclusiveFlock
+	// ExclusiveBoth sets TIOCEXCL and acquires the advisory flock lock.
+	ExclusiveBoth
+)
+
+// DefaultUUCPLockDir is the default directory of the UUCP lock files, see WithUUCPLock.
+const DefaultUUCPLockDir = "/var/lock"
+
+// LockHolder describes the holder of the lock which prevents the port from being opened.
+// It is wrapped by the PortError with PortBusy code where available.
+type LockHolder struct {
+	PID  int    // Process ID of the holder, zero if unknown
+	Lock string // Kind of the lock: "flock" or "uucp"
+}
+
+func (h *LockHolder) Error() string {
+	if h.PID == 0 {
+		return h.Lock + " lock is held by unknown process"
+	}
+	return fmt.Sprintf("%s lock is held by process %d", h.Lock, h.PID)
+}
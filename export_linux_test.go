@@ -0,0 +1,32 @@
+//go:build linux && !android
+
+package serial
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// OpenPTY exports openPTY to the external tests.
+var OpenPTY = openPTY
+
+// openPTY opens a pseudo-terminal pair and returns the master side and the slave device name.
+func openPTY(t *testing.T) (*os.File, string) {
+	t.Helper()
+
+	m, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+
+	require.NoError(t, unix.IoctlSetPointerInt(int(m.Fd()), unix.TIOCSPTLCK, 0))
+	n, err := unix.IoctlGetInt(int(m.Fd()), unix.TIOCGPTN)
+	require.NoError(t, err)
+
+	return m, fmt.Sprintf("/dev/pts/%d", n)
+}
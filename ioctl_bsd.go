@@ -0,0 +1,34 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+//go:build darwin || freebsd || openbsd
+
+package serial
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// tcdrain waits until all output written to fd has been transmitted.
+func tcdrain(fd int) error {
+	return unix.IoctlSetInt(fd, unix.TIOCDRAIN, 0)
+}
+
+// tiocgicount always fails, TIOCGICOUNT is linux only.
+func tiocgicount(_ int) (Counters, error) {
+	return Counters{}, unix.ENOTTY
+}
+
+// getRS485 always fails, kernel RS-485 mode is linux only.
+func getRS485(_ int) (RS485Config, error) {
+	return RS485Config{}, unix.ENOTTY
+}
+
+// setRS485 always fails, kernel RS-485 mode is linux only.
+func setRS485(_ int, _ RS485Config) error {
+	return unix.ENOTTY
+}
@@ -0,0 +1,112 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+//go:build linux
+
+package serial
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	serRS485Enabled      = 1 << 0 // SER_RS485_ENABLED
+	serRS485RTSOnSend    = 1 << 1 // SER_RS485_RTS_ON_SEND
+	serRS485RTSAfterSend = 1 << 2 // SER_RS485_RTS_AFTER_SEND
+	serRS485RxDuringTx   = 1 << 4 // SER_RS485_RX_DURING_TX
+	serRS485TerminateBus = 1 << 5 // SER_RS485_TERMINATE_BUS
+)
+
+// serialRS485 is struct serial_rs485 from linux/serial.h.
+type serialRS485 struct {
+	flags              uint32
+	delayRTSBeforeSend uint32
+	delayRTSAfterSend  uint32
+	padding            [5]uint32
+}
+
+// serialICounter is struct serial_icounter_struct from linux/serial.h.
+type serialICounter struct {
+	cts, dsr, rng, dcd          int32
+	rx, tx                      int32
+	frame, overrun, parity, brk int32
+	bufOverrun                  int32
+	reserved                    [9]int32
+}
+
+func ioctlPtr(fd int, req uint, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// tcdrain waits until all output written to fd has been transmitted.
+func tcdrain(fd int) error {
+	return unix.IoctlSetInt(fd, unix.TCSBRK, 1)
+}
+
+func tiocgicount(fd int) (Counters, error) {
+	var c serialICounter
+	if err := ioctlPtr(fd, unix.TIOCGICOUNT, unsafe.Pointer(&c)); err != nil {
+		return Counters{}, err
+	}
+	return Counters{
+		RX:            uint32(c.rx),
+		TX:            uint32(c.tx),
+		Frame:         uint32(c.frame),
+		Overrun:       uint32(c.overrun),
+		Parity:        uint32(c.parity),
+		Break:         uint32(c.brk),
+		BufferOverrun: uint32(c.bufOverrun),
+		CTS:           uint32(c.cts),
+		DSR:           uint32(c.dsr),
+		RI:            uint32(c.rng),
+		DCD:           uint32(c.dcd),
+	}, nil
+}
+
+func getRS485(fd int) (RS485Config, error) {
+	var rs serialRS485
+	if err := ioctlPtr(fd, unix.TIOCGRS485, unsafe.Pointer(&rs)); err != nil {
+		return RS485Config{}, err
+	}
+	return RS485Config{
+		Enabled:            rs.flags&serRS485Enabled != 0,
+		RTSOnSend:          rs.flags&serRS485RTSOnSend != 0,
+		RTSAfterSend:       rs.flags&serRS485RTSAfterSend != 0,
+		DelayRTSBeforeSend: int(rs.delayRTSBeforeSend),
+		DelayRTSAfterSend:  int(rs.delayRTSAfterSend),
+		RxDuringTx:         rs.flags&serRS485RxDuringTx != 0,
+		TerminateBus:       rs.flags&serRS485TerminateBus != 0,
+	}, nil
+}
+
+func setRS485(fd int, c RS485Config) error {
+	rs := serialRS485{
+		delayRTSBeforeSend: uint32(c.DelayRTSBeforeSend),
+		delayRTSAfterSend:  uint32(c.DelayRTSAfterSend),
+	}
+	if c.Enabled {
+		rs.flags |= serRS485Enabled
+	}
+	if c.RTSOnSend {
+		rs.flags |= serRS485RTSOnSend
+	}
+	if c.RTSAfterSend {
+		rs.flags |= serRS485RTSAfterSend
+	}
+	if c.RxDuringTx {
+		rs.flags |= serRS485RxDuringTx
+	}
+	if c.TerminateBus {
+		rs.flags |= serRS485TerminateBus
+	}
+	return ioctlPtr(fd, unix.TIOCSRS485, unsafe.Pointer(&rs))
+}
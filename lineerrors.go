@@ -0,0 +1,41 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+const (
+	// LineBreak the break condition has been received.
+	LineBreak LineEventKind = iota + 1
+	// LineParityError a character with parity error has been received.
+	LineParityError
+	// LineFramingError a character with framing error has been received.
+	LineFramingError
+)
+
+// LineEventKind describes a kind of the line event.
+type LineEventKind int
+
+func (k LineEventKind) String() string {
+	switch k {
+	case LineBreak:
+		return "break"
+	case LineParityError:
+		return "parity error"
+	case LineFramingError:
+		return "framing error"
+	default:
+		return "unknown line event"
+	}
+}
+
+// LineEvent describes the break condition or the line error received within the data stream,
+// see Port.ReadWithErrors() and WithLineErrorReporting() option.
+type LineEvent struct {
+	Kind   LineEventKind
+	Offset int  // Offset in the data read, before which the event has been received
+	Char   byte // Erroneous character, it is not included into the data read (zero for LineBreak)
+}
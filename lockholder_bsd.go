@@ -0,0 +1,15 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+//go:build darwin || freebsd || openbsd
+
+package serial
+
+// flockHolder returns the PID of the process holding flock lock on the file h, zero if unknown.
+func flockHolder(_ int) int {
+	return 0 // not available
+}
@@ -0,0 +1,61 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+var procLocksPath = "/proc/locks"
+
+// flockHolder returns the PID of the process holding flock lock on the file h, zero if unknown.
+func flockHolder(h int) int {
+	var st unix.Stat_t
+	if err := unix.Fstat(h, &st); err != nil {
+		return 0
+	}
+
+	f, err := os.Open(procLocksPath)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	dev := uint64(st.Dev) //nolint:unconvert // not uint64 on every arch
+	return parseProcLocks(f, unix.Major(dev), unix.Minor(dev), uint64(st.Ino))
+}
+
+// parseProcLocks looks for the flock lock on the file in /proc/locks formatted content, e.g.
+//
+//	1: FLOCK  ADVISORY  WRITE 1234 00:05:123 0 EOF
+//	1: -> FLOCK  ADVISORY  WRITE 5678 00:05:123 0 EOF
+//
+// where the file is identified by its device major and minor numbers (hex) and inode,
+// the waiting processes are marked with the "->" and skipped.
+func parseProcLocks(r io.Reader, major, minor uint32, ino uint64) int {
+	file := fmt.Sprintf("%02x:%02x:%d", major, minor, ino)
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 6 || fields[1] != "FLOCK" || fields[5] != file {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[4]); err == nil {
+			return pid
+		}
+	}
+	return 0
+}
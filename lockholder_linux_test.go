@@ -0,0 +1,21 @@
+package serial
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProcLocks(t *testing.T) {
+	const locks = `1: POSIX  ADVISORY  WRITE 100 00:05:123 0 EOF
+2: FLOCK  ADVISORY  WRITE 200 fd:01:123 0 EOF
+3: FLOCK  ADVISORY  WRITE 300 00:05:123 0 EOF
+3: -> FLOCK  ADVISORY  WRITE 400 00:05:123 0 EOF
+4: OFDLCK ADVISORY  READ  -1 00:05:456 0 EOF
+`
+	assert.Equal(t, 300, parseProcLocks(strings.NewReader(locks), 0, 5, 123))
+	assert.Equal(t, 200, parseProcLocks(strings.NewReader(locks), 0xfd, 1, 123))
+	assert.Zero(t, parseProcLocks(strings.NewReader(locks), 0, 5, 456))
+	assert.Zero(t, parseProcLocks(strings.NewReader(""), 0, 5, 123))
+}
@@ -0,0 +1,455 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parityLetters are indexed by Parity.
+const parityLetters = "NOEMS"
+
+var (
+	// Data bits, parity and stop bits e.g. 8N1, 7E1.5.
+	frameRx = regexp.MustCompile(`^(\d)([a-z])(\d(?:\.5)?)$`)
+	// Parity, data bits and stop bits e.g. N81.
+	frameParityFirstRx = regexp.MustCompile(`^([a-z])(\d)(\d(?:\.5)?)$`)
+	// Windows mode.com device prefix e.g. COM1.
+	deviceRx = regexp.MustCompile(`^com\d+$`)
+)
+
+// Windows mode.com accepts the first two digits of the standard baud rates.
+var modeComBaudRates = map[int]int{
+	11: 110,
+	15: 150,
+	30: 300,
+	60: 600,
+	12: 1200,
+	24: 2400,
+	48: 4800,
+	96: 9600,
+	19: 19200,
+}
+
+// ParseMode parses the serial port mode string and returns the options for the settings found in it,
+// the settings not mentioned are left as is. The common notations are accepted:
+//
+//	115200,8N1          9600-7E2           57600_N81         38400,8,N,1 rtscts
+//	9600,n,8,1,xonxoff  115200 8N1 hw      4800 8N1.5        2400 7O1 xon/xoff
+//	COM1: baud=96 parity=n data=8 stop=1 octs=on rts=hs    (windows mode.com)
+//	COM1: 96,n,8,1                                         (windows mode.com, positional)
+//	115200 cs8 -parenb -cstopb crtscts                     (stty)
+//
+// Flow control suffixes are none, rtscts (rts/cts, crtscts, hw, hardware), dtrdsr (dtr/dsr),
+// xonxoff (xon/xoff, sw, software), ixon, ixoff and ixany, the stty ones may be negated (-ixon).
+// The none suffix turns off both the hardware and the software flow control. The software flow control
+// flags not mentioned are left as is.
+func ParseMode(s string) ([]Option, error) {
+	var m modeParser
+	if err := m.parse(s); err != nil {
+		return nil, err
+	}
+	return m.options(), nil
+}
+
+// ParseConfig parses the serial port mode string (see ParseMode) and applies it to the default configuration.
+// The platform support of the settings is not checked, see Config.Validate.
+func ParseConfig(s string) (Config, error) {
+	opts, err := ParseMode(s)
+	if err != nil {
+		return Config{}, err
+	}
+	return NewConfig(opts...), nil
+}
+
+// String returns the configuration in the canonical mode string form e.g. "115200,8N1,rtscts",
+// see ParseMode.
+func (c Config) String() string {
+	var b strings.Builder
+
+	parity := byte('?')
+	if c.Parity >= 0 && int(c.Parity) < len(parityLetters) {
+		parity = parityLetters[c.Parity]
+	}
+	fmt.Fprintf(&b, "%d,%d%c%s", c.BaudRate, c.DataBits, parity, c.StopBits)
+
+	switch c.FlowControl {
+	case NoFlowControl:
+	case RTSCTSFlowControl:
+		b.WriteString(",rtscts")
+	case DTRDSRFlowControl:
+		b.WriteString(",dtrdsr")
+	default:
+		fmt.Fprintf(&b, ",%s", c.FlowControl)
+	}
+
+	switch {
+	case c.SoftwareFlowControl.Input && c.SoftwareFlowControl.Output:
+		b.WriteString(",xonxoff")
+	case c.SoftwareFlowControl.Output:
+		b.WriteString(",ixon")
+	case c.SoftwareFlowControl.Input:
+		b.WriteString(",ixoff")
+	}
+	if c.SoftwareFlowControl.Any {
+		b.WriteString(",ixany")
+	}
+	return b.String()
+}
+
+type modeField int
+
+const (
+	modeBaudRate modeField = iota
+	modeDataBits
+	modeParity
+	modeStopBits
+	modeFlowControl
+	modeRS485
+	modeSoftInput
+	modeSoftOutput
+	modeSoftAny
+	modeFieldsCount
+)
+
+type modeParser struct {
+	values [modeFieldsCount]int
+	seen   [modeFieldsCount]bool
+
+	// mode.com notation, the two-digit baud rates are expanded (e.g. "COM1: 96,n,8,1")
+	modeCom bool
+
+	// stty parenb and parodd flags, 0 if not mentioned, 1 if set and -1 if cleared
+	parenb int
+	parodd int
+}
+
+func (m *modeParser) parse(s string) error {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return &PortError{code: InvalidMode, wrapped: fmt.Errorf("empty mode string")}
+	}
+	s = strings.NewReplacer("rts/cts", "rtscts", "dtr/dsr", "dtrdsr", "xon/xoff", "xonxoff").Replace(s)
+
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '/' || r == ':' || r == ';' || r == '_'
+	})
+	for i, f := range fields {
+		if i == 0 && deviceRx.MatchString(f) {
+			m.modeCom = true
+			continue
+		}
+		// Leading dash negates stty flags, elsewhere it is a separator
+		if strings.HasPrefix(f, "-") {
+			if err := m.token(f); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, t := range strings.Split(f, "-") {
+			if t == "" {
+				continue
+			}
+			if err := m.token(t); err != nil {
+				return err
+			}
+		}
+	}
+	return m.finishParity()
+}
+
+//nolint:cyclop
+func (m *modeParser) token(t string) error {
+	if k, v, ok := strings.Cut(t, "="); ok {
+		return m.keyValue(k, v)
+	}
+	if sm := frameRx.FindStringSubmatch(t); sm != nil {
+		return m.frame(sm[1], sm[2], sm[3])
+	}
+	if sm := frameParityFirstRx.FindStringSubmatch(t); sm != nil {
+		return m.frame(sm[2], sm[1], sm[3])
+	}
+
+	switch t {
+	case "none", "nofc":
+		m.flag(modeSoftInput, false)
+		m.flag(modeSoftOutput, false)
+		return m.set(modeFlowControl, int(NoFlowControl))
+	case "rtscts", "crtscts", "hw", "hardware":
+		return m.set(modeFlowControl, int(RTSCTSFlowControl))
+	case "dtrdsr", "cdtrdsr":
+		return m.set(modeFlowControl, int(DTRDSRFlowControl))
+	case "xonxoff", "sw", "software":
+		m.flag(modeSoftInput, true)
+		m.flag(modeSoftOutput, true)
+		return nil
+	case "ixon", "-ixon":
+		m.flag(modeSoftOutput, t[0] != '-')
+		return nil
+	case "ixoff", "-ixoff":
+		m.flag(modeSoftInput, t[0] != '-')
+		return nil
+	case "ixany", "-ixany":
+		m.flag(modeSoftAny, t[0] != '-')
+		return nil
+	case "-crtscts":
+		return m.set(modeFlowControl, int(NoFlowControl))
+	case "parenb":
+		m.parenb = 1
+		return nil
+	case "-parenb":
+		m.parenb = -1
+		return nil
+	case "parodd":
+		m.parodd = 1
+		return nil
+	case "-parodd":
+		m.parodd = -1
+		return nil
+	case "cstopb":
+		return m.set(modeStopBits, int(TwoStopBits))
+	case "-cstopb":
+		return m.set(modeStopBits, int(OneStopBit))
+	case "cs5", "cs6", "cs7", "cs8":
+		return m.set(modeDataBits, int(t[2]-'0'))
+	}
+
+	if p, ok := parseParity(t); ok {
+		return m.set(modeParity, int(p))
+	}
+	switch t {
+	case "1", "1.5", "2":
+		sb, _ := parseStopBits(t)
+		return m.set(modeStopBits, int(sb))
+	case "5", "6", "7", "8":
+		return m.set(modeDataBits, int(t[0]-'0'))
+	}
+	if n, err := strconv.Atoi(t); err == nil {
+		if n <= 0 {
+			return &PortError{code: InvalidSpeed, wrapped: fmt.Errorf("baud rate %q", t)}
+		}
+		if b, ok := modeComBaudRates[n]; ok && m.modeCom {
+			n = b
+		}
+		return m.set(modeBaudRate, n)
+	}
+	return &PortError{code: InvalidMode, wrapped: fmt.Errorf("unexpected %q", t)}
+}
+
+// keyValue parses the windows mode.com settings.
+//
+//nolint:cyclop
+func (m *modeParser) keyValue(k, v string) error {
+	switch k {
+	case "baud":
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return &PortError{code: InvalidSpeed, wrapped: fmt.Errorf("baud rate %q", v)}
+		}
+		if b, ok := modeComBaudRates[n]; ok {
+			n = b
+		}
+		return m.set(modeBaudRate, n)
+	case "parity":
+		p, ok := parseParity(v)
+		if !ok {
+			return &PortError{code: InvalidParity, wrapped: fmt.Errorf("parity %q", v)}
+		}
+		return m.set(modeParity, int(p))
+	case "data":
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 5 || n > 8 {
+			return &PortError{code: InvalidDataBits, wrapped: fmt.Errorf("data bits %q", v)}
+		}
+		return m.set(modeDataBits, n)
+	case "stop":
+		sb, ok := parseStopBits(v)
+		if !ok {
+			return &PortError{code: InvalidStopBits, wrapped: fmt.Errorf("stop bits %q", v)}
+		}
+		return m.set(modeStopBits, int(sb))
+	case "xon":
+		on, err := parseOnOff(k, v)
+		if err != nil {
+			return err
+		}
+		m.flag(modeSoftInput, on)
+		m.flag(modeSoftOutput, on)
+		return nil
+	case "octs", "odsr":
+		on, err := parseOnOff(k, v)
+		if err != nil || !on {
+			return err
+		}
+		if k == "octs" {
+			return m.set(modeFlowControl, int(RTSCTSFlowControl))
+		}
+		return m.set(modeFlowControl, int(DTRDSRFlowControl))
+	case "rts", "dtr":
+		switch v {
+		case "on", "off", "":
+			return nil // line state, not a part of the configuration
+		case "hs":
+			if k == "rts" {
+				return m.set(modeFlowControl, int(RTSCTSFlowControl))
+			}
+			return m.set(modeFlowControl, int(DTRDSRFlowControl))
+		case "tg":
+			if k == "rts" {
+				return m.set(modeRS485, 1)
+			}
+		}
+		return &PortError{code: InvalidMode, wrapped: fmt.Errorf("unexpected %s=%s", k, v)}
+	case "to", "idsr":
+		_, err := parseOnOff(k, v) // not supported, accepted for compatibility only
+		return err
+	}
+	return &PortError{code: InvalidMode, wrapped: fmt.Errorf("unexpected %s=%s", k, v)}
+}
+
+func (m *modeParser) frame(data, parity, stop string) error {
+	if data < "5" || data > "8" {
+		return &PortError{code: InvalidDataBits, wrapped: fmt.Errorf("data bits %q", data)}
+	}
+	p, ok := parseParity(parity)
+	if !ok {
+		return &PortError{code: InvalidParity, wrapped: fmt.Errorf("parity %q", parity)}
+	}
+	sb, ok := parseStopBits(stop)
+	if !ok {
+		return &PortError{code: InvalidStopBits, wrapped: fmt.Errorf("stop bits %q", stop)}
+	}
+	if err := m.set(modeDataBits, int(data[0]-'0')); err != nil {
+		return err
+	}
+	if err := m.set(modeParity, int(p)); err != nil {
+		return err
+	}
+	return m.set(modeStopBits, int(sb))
+}
+
+func (m *modeParser) finishParity() error {
+	switch {
+	case m.parenb > 0 && m.parodd > 0:
+		return m.set(modeParity, int(OddParity))
+	case m.parenb > 0:
+		return m.set(modeParity, int(EvenParity))
+	case m.parenb < 0:
+		return m.set(modeParity, int(NoParity))
+	}
+	return nil
+}
+
+// set sets the field value, a field may be repeated with the same value only.
+func (m *modeParser) set(f modeField, v int) error {
+	if m.seen[f] && m.values[f] != v {
+		return &PortError{code: InvalidMode, wrapped: fmt.Errorf("conflicting %s settings", modeFieldNames[f])}
+	}
+	m.seen[f] = true
+	m.values[f] = v
+	return nil
+}
+
+// flag sets the software flow control flag, the last mention wins (e.g. "xonxoff -ixon").
+func (m *modeParser) flag(f modeField, on bool) {
+	m.seen[f] = true
+	m.values[f] = 0
+	if on {
+		m.values[f] = 1
+	}
+}
+
+func (m *modeParser) options() []Option {
+	var opts []Option
+	if m.seen[modeBaudRate] {
+		opts = append(opts, WithBaudrate(m.values[modeBaudRate]))
+	}
+	if m.seen[modeDataBits] {
+		opts = append(opts, WithDataBits(m.values[modeDataBits]))
+	}
+	if m.seen[modeParity] {
+		opts = append(opts, WithParity(Parity(m.values[modeParity])))
+	}
+	if m.seen[modeStopBits] {
+		opts = append(opts, WithStopBits(StopBits(m.values[modeStopBits])))
+	}
+	if m.seen[modeFlowControl] {
+		opts = append(opts, WithFlowControl(FlowControl(m.values[modeFlowControl])))
+	}
+	if m.seen[modeSoftInput] || m.seen[modeSoftOutput] || m.seen[modeSoftAny] {
+		opts = append(opts, m.softwareFlowControl)
+	}
+	if m.seen[modeRS485] {
+		opts = append(opts, WithRS485(RS485Config{Enabled: true, RTSOnSend: true}))
+	}
+	return opts
+}
+
+// softwareFlowControl applies the software flow control flags mentioned, the other settings are left as is.
+func (m *modeParser) softwareFlowControl(c *Config) {
+	fc := c.SoftwareFlowControl
+	if m.seen[modeSoftInput] {
+		fc.Input = m.values[modeSoftInput] != 0
+	}
+	if m.seen[modeSoftOutput] {
+		fc.Output = m.values[modeSoftOutput] != 0
+	}
+	if m.seen[modeSoftAny] {
+		fc.Any = m.values[modeSoftAny] != 0
+	}
+	WithSoftwareFlowControl(fc)(c)
+}
+
+var modeFieldNames = [modeFieldsCount]string{
+	modeBaudRate:    "baud rate",
+	modeDataBits:    "data bits",
+	modeParity:      "parity",
+	modeStopBits:    "stop bits",
+	modeFlowControl: "flow control",
+	modeRS485:       "rs485",
+	modeSoftInput:   "ixoff",
+	modeSoftOutput:  "ixon",
+	modeSoftAny:     "ixany",
+}
+
+func parseParity(s string) (Parity, bool) {
+	if len(s) == 1 {
+		if i := strings.IndexByte(parityLetters, s[0]-'a'+'A'); i >= 0 {
+			return Parity(i), true
+		}
+		return 0, false
+	}
+	for i, name := range parityNames {
+		if s == name {
+			return Parity(i), true
+		}
+	}
+	return 0, false
+}
+
+func parseStopBits(s string) (StopBits, bool) {
+	for i, name := range stopBitsNames {
+		if s == name {
+			return StopBits(i), true
+		}
+	}
+	return 0, false
+}
+
+func parseOnOff(k, v string) (bool, error) {
+	switch v {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, &PortError{code: InvalidMode, wrapped: fmt.Errorf("unexpected %s=%s", k, v)}
+}
@@ -0,0 +1,219 @@
+package serial_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/albenik/go-serial/v2"
+)
+
+func TestParseConfig(t *testing.T) {
+	rtscts := serial.RTSCTSFlowControl
+	xonxoff := serial.SoftwareFlowControl{Input: true, Output: true}
+
+	tests := []struct {
+		mode     string
+		baudRate int
+		dataBits int
+		parity   serial.Parity
+		stopBits serial.StopBits
+		flow     serial.FlowControl
+		soft     serial.SoftwareFlowControl
+		rs485    bool
+	}{
+		{mode: "115200,8N1", baudRate: 115200, dataBits: 8},
+		{mode: "9600-7E2", baudRate: 9600, dataBits: 7, parity: serial.EvenParity, stopBits: serial.TwoStopBits},
+		{mode: "9600 8N1", baudRate: 9600, dataBits: 8},
+		{mode: "  19200 8n1  ", baudRate: 19200, dataBits: 8},
+		{mode: "57600_N81", baudRate: 57600, dataBits: 8},
+		{mode: "9600_E71", baudRate: 9600, dataBits: 7, parity: serial.EvenParity},
+		{mode: "38400,8,N,1", baudRate: 38400, dataBits: 8},
+		{mode: "9600,n,8,1", baudRate: 9600, dataBits: 8},
+		{mode: "19200/8-N-1", baudRate: 19200, dataBits: 8},
+		{mode: "4800 8N1.5", baudRate: 4800, dataBits: 8, stopBits: serial.OnePointFiveStopBits},
+		{mode: "1200 7O1", baudRate: 1200, dataBits: 7, parity: serial.OddParity},
+		{mode: "300,7M1", baudRate: 300, dataBits: 7, parity: serial.MarkParity},
+		{mode: "300,7S2", baudRate: 300, dataBits: 7, parity: serial.SpaceParity, stopBits: serial.TwoStopBits},
+		{mode: "2400,8,none,1", baudRate: 2400, dataBits: 8},
+		{mode: "2400,8,even,1", baudRate: 2400, dataBits: 8, parity: serial.EvenParity},
+		{mode: "8N1", baudRate: 9600, dataBits: 8},
+		{mode: "230400", baudRate: 230400, dataBits: 8},
+		{mode: "5N2", baudRate: 9600, dataBits: 5, stopBits: serial.TwoStopBits},
+		{mode: "115200 8N1 rtscts", baudRate: 115200, dataBits: 8, flow: rtscts},
+		{mode: "9600,8N1,RTS/CTS", baudRate: 9600, dataBits: 8, flow: rtscts},
+		{mode: "460800 8N1 hw", baudRate: 460800, dataBits: 8, flow: rtscts},
+		{mode: "921600-8n1-rtscts", baudRate: 921600, dataBits: 8, flow: rtscts},
+		{mode: "9600 8N1 dtr/dsr", baudRate: 9600, dataBits: 8, flow: serial.DTRDSRFlowControl},
+		{mode: "300 7E1 none", baudRate: 300, dataBits: 7, parity: serial.EvenParity},
+		{mode: "2400 7O1 xon/xoff", baudRate: 2400, dataBits: 7, parity: serial.OddParity, soft: xonxoff},
+		{mode: "1200,7,E,1,xonxoff", baudRate: 1200, dataBits: 7, parity: serial.EvenParity, soft: xonxoff},
+		{mode: "9600,8N1,ixon", baudRate: 9600, dataBits: 8, soft: serial.SoftwareFlowControl{Output: true}},
+		{mode: "9600,8N1,rtscts,xonxoff", baudRate: 9600, dataBits: 8, flow: rtscts, soft: xonxoff},
+		{mode: "COM1: baud=96 parity=n data=8 stop=1", baudRate: 9600, dataBits: 8},
+		{mode: "COM3:9600,N,8,1", baudRate: 9600, dataBits: 8},
+		{mode: "COM1: 96,n,8,1", baudRate: 9600, dataBits: 8},
+		{mode: "COM2: 12,e,7,2", baudRate: 1200, dataBits: 7, parity: serial.EvenParity, stopBits: serial.TwoStopBits},
+		{mode: "96 8N1", baudRate: 96, dataBits: 8},
+		{mode: "com4 baud=19 parity=e data=7 stop=2", baudRate: 19200, dataBits: 7, parity: serial.EvenParity, stopBits: serial.TwoStopBits},
+		{mode: "baud=115200 parity=n data=8 stop=1 octs=on rts=hs", baudRate: 115200, dataBits: 8, flow: rtscts},
+		{
+			mode:     "baud=9600 parity=o data=8 stop=1.5 xon=on dtr=on",
+			baudRate: 9600, dataBits: 8, parity: serial.OddParity, stopBits: serial.OnePointFiveStopBits, soft: xonxoff,
+		},
+		{mode: "baud=9600 data=8 odsr=on dtr=hs to=off", baudRate: 9600, dataBits: 8, flow: serial.DTRDSRFlowControl},
+		{mode: "baud=9600 rts=tg", baudRate: 9600, dataBits: 8, rs485: true},
+		{mode: "115200 cs8 -parenb -cstopb crtscts", baudRate: 115200, dataBits: 8, flow: rtscts},
+		{mode: "9600 cs7 parenb -parodd cstopb", baudRate: 9600, dataBits: 7, parity: serial.EvenParity, stopBits: serial.TwoStopBits},
+		{mode: "9600 cs7 parenb parodd", baudRate: 9600, dataBits: 7, parity: serial.OddParity},
+		{mode: "9600 cs8 -crtscts -ixon -ixoff", baudRate: 9600, dataBits: 8},
+		{mode: "9600 8N1 xonxoff -ixon", baudRate: 9600, dataBits: 8, soft: serial.SoftwareFlowControl{Input: true}},
+		{mode: "9600 8N1 xonxoff -ixoff", baudRate: 9600, dataBits: 8, soft: serial.SoftwareFlowControl{Output: true}},
+		{mode: "9600 8N1 ixon ixany", baudRate: 9600, dataBits: 8, soft: serial.SoftwareFlowControl{Output: true, Any: true}},
+		{mode: "9600 8N1 8N1", baudRate: 9600, dataBits: 8},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.mode, func(t *testing.T) {
+			c, err := serial.ParseConfig(tt.mode)
+			require.NoError(t, err)
+
+			want := serial.DefaultConfig()
+			want.BaudRate = tt.baudRate
+			want.DataBits = tt.dataBits
+			want.Parity = tt.parity
+			want.StopBits = tt.stopBits
+			want.FlowControl = tt.flow
+			want.SoftwareFlowControl = tt.soft
+			if tt.rs485 {
+				want.RS485 = serial.RS485Config{Enabled: true, RTSOnSend: true}
+			}
+			assert.Equal(t, want, c)
+		})
+	}
+}
+
+func TestParseMode_Error(t *testing.T) {
+	tests := []struct {
+		mode string
+		code serial.PortErrorCode
+	}{
+		{mode: "", code: serial.InvalidMode},
+		{mode: "   ", code: serial.InvalidMode},
+		{mode: "foo", code: serial.InvalidMode},
+		{mode: "9600,9N1", code: serial.InvalidDataBits},
+		{mode: "9600,4N1", code: serial.InvalidDataBits},
+		{mode: "9600,8X1", code: serial.InvalidParity},
+		{mode: "9600,8N3", code: serial.InvalidStopBits},
+		{mode: "9600,X81", code: serial.InvalidParity},
+		{mode: "0,8N1", code: serial.InvalidSpeed},
+		{mode: "9600 19200", code: serial.InvalidMode},
+		{mode: "9600 8N1 7E1", code: serial.InvalidMode},
+		{mode: "9600,8N1,rtscts,dtrdsr", code: serial.InvalidMode},
+		{mode: "9600 cs7 parenb 8N1", code: serial.InvalidMode},
+		{mode: "baud=abc", code: serial.InvalidSpeed},
+		{mode: "parity=q", code: serial.InvalidParity},
+		{mode: "data=9", code: serial.InvalidDataBits},
+		{mode: "stop=3", code: serial.InvalidStopBits},
+		{mode: "xon=maybe", code: serial.InvalidMode},
+		{mode: "rts=xx", code: serial.InvalidMode},
+		{mode: "speed=9600", code: serial.InvalidMode},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.mode, func(t *testing.T) {
+			opts, err := serial.ParseMode(tt.mode)
+			assert.Nil(t, opts)
+			var portErr *serial.PortError
+			require.ErrorAs(t, err, &portErr)
+			assert.Equal(t, tt.code, portErr.Code())
+		})
+	}
+}
+
+func TestParseMode_SoftwareFlowControl(t *testing.T) {
+	xonxoff := serial.SoftwareFlowControl{Input: true, Output: true, Any: true, XON: 0x01, XOFF: 0x02}
+
+	tests := []struct {
+		mode string
+		want serial.SoftwareFlowControl
+	}{
+		{mode: "-ixon -ixoff", want: serial.SoftwareFlowControl{Any: true, XON: 0x01, XOFF: 0x02}},
+		{mode: "9600 8N1 none", want: serial.SoftwareFlowControl{Any: true, XON: 0x01, XOFF: 0x02}},
+		{mode: "baud=9600 xon=off", want: serial.SoftwareFlowControl{Any: true, XON: 0x01, XOFF: 0x02}},
+		{mode: "-ixon -ixoff -ixany", want: serial.SoftwareFlowControl{XON: 0x01, XOFF: 0x02}},
+		{mode: "-ixon", want: serial.SoftwareFlowControl{Input: true, Any: true, XON: 0x01, XOFF: 0x02}},
+		{mode: "9600 -crtscts", want: xonxoff},
+		{mode: "9600 8N1", want: xonxoff},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.mode, func(t *testing.T) {
+			opts, err := serial.ParseMode(tt.mode)
+			require.NoError(t, err)
+
+			c := serial.NewConfig(append([]serial.Option{serial.WithSoftwareFlowControl(xonxoff)}, opts...)...)
+			assert.Equal(t, tt.want, c.SoftwareFlowControl)
+		})
+	}
+}
+
+func TestParseMode_Partial(t *testing.T) {
+	opts, err := serial.ParseMode("7E1")
+	require.NoError(t, err)
+
+	c := serial.NewConfig(append([]serial.Option{serial.WithBaudrate(115200)}, opts...)...)
+	assert.Equal(t, 115200, c.BaudRate)
+	assert.Equal(t, 7, c.DataBits)
+	assert.Equal(t, serial.EvenParity, c.Parity)
+}
+
+func TestConfig_String(t *testing.T) {
+	tests := []struct {
+		config serial.Config
+		want   string
+	}{
+		{config: serial.DefaultConfig(), want: "9600,8N1"},
+		{
+			config: serial.NewConfig(serial.WithBaudrate(115200), serial.WithFlowControl(serial.RTSCTSFlowControl)),
+			want:   "115200,8N1,rtscts",
+		},
+		{
+			config: serial.NewConfig(
+				serial.WithDataBits(7),
+				serial.WithParity(serial.EvenParity),
+				serial.WithStopBits(serial.OnePointFiveStopBits),
+				serial.WithFlowControl(serial.DTRDSRFlowControl),
+			),
+			want: "9600,7E1.5,dtrdsr",
+		},
+		{
+			config: serial.NewConfig(serial.WithSoftwareFlowControl(serial.SoftwareFlowControl{Input: true, Output: true})),
+			want:   "9600,8N1,xonxoff",
+		},
+		{
+			config: serial.NewConfig(serial.WithSoftwareFlowControl(serial.SoftwareFlowControl{Input: true})),
+			want:   "9600,8N1,ixoff",
+		},
+		{
+			config: serial.NewConfig(serial.WithSoftwareFlowControl(serial.SoftwareFlowControl{Output: true, Any: true})),
+			want:   "9600,8N1,ixon,ixany",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.config.String())
+
+			// Canonical form is parsed back to the same config
+			c, err := serial.ParseConfig(tt.config.String())
+			require.NoError(t, err)
+			assert.Equal(t, tt.config, c)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// ModemCTS selects ClearToSend line.
+	ModemCTS ModemStatusMask = 1 << iota
+	// ModemDSR selects DataSetReady line.
+	ModemDSR
+	// ModemRI selects RingIndicator line.
+	ModemRI
+	// ModemDCD selects DataCarrierDetect line.
+	ModemDCD
+
+	// ModemAll selects all the modem status lines.
+	ModemAll = ModemCTS | ModemDSR | ModemRI | ModemDCD
+)
+
+// modemStatusPollInterval is used where waiting for modem status change is not supported by the driver.
+const modemStatusPollInterval = 50 * time.Millisecond
+
+// ModemStatusMask is a set of modem status lines, zero value means all the lines.
+type ModemStatusMask int
+
+// ModemStatusEvent describes modem status lines transition, see Port.WatchModemStatus().
+type ModemStatusEvent struct {
+	Time    time.Time
+	Status  ModemStatusBits
+	Changed ModemStatusMask // Lines transitioned since the previous event, may be back to the previous level
+	Err     error           // The error stopped the watching, set for the last event only
+}
+
+// WatchModemStatus reports the modem status lines transitions selected by mask until ctx is done.
+// The returned channel is closed when ctx is done or an error occurred (e.g. the port is closed),
+// the error is reported with the last event (see ModemStatusEvent.Err) before.
+//
+// The transitions are detected the same way as by WaitModemStatusChange (the linux line transition counters
+// are polled instead of TIOCMIWAIT waiting), but nothing is missed between the events: a short pulse is reported
+// with the line in Changed even if Status shows the line at its previous level.
+func (p *Port) WatchModemStatus(ctx context.Context, mask ModemStatusMask) (_ <-chan ModemStatusEvent, err error) {
+	defer p.wrapErr("watch modem status", &err)
+
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	w, err := p.newModemWatcher(mask)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ModemStatusEvent)
+	go func() {
+		defer close(ch)
+		defer w.close()
+		for {
+			changed, status, err := p.waitModemWatcher(ctx, w)
+			if err != nil {
+				if ctx.Err() == nil {
+					sendModemStatusEvent(ctx, ch, ModemStatusEvent{Time: time.Now(), Err: err})
+				}
+				return
+			}
+			if !sendModemStatusEvent(ctx, ch, ModemStatusEvent{Time: time.Now(), Status: *status, Changed: changed}) {
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// waitModemWatcher waits for the transitions detected by w and returns them along with the current status.
+func (p *Port) waitModemWatcher(ctx context.Context, w *modemWatcher) (_ ModemStatusMask, _ *ModemStatusBits, err error) {
+	defer p.wrapErr("watch modem status", &err)
+
+	changed, err := w.wait(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	status, err := p.GetModemStatusBits()
+	if err != nil {
+		return 0, nil, err
+	}
+	return changed, status, nil
+}
+
+// sendModemStatusEvent sends the event, false is returned if ctx is done before.
+func sendModemStatusEvent(ctx context.Context, ch chan<- ModemStatusEvent, e ModemStatusEvent) bool {
+	select {
+	case ch <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pollModem calls changed every modemStatusPollInterval until it reports the change or fails,
+// ctx is done or the port is closed. Nothing is left blocked in the background, unlike the waiting ioctls.
+func (p *Port) pollModem(ctx context.Context, changed func() (bool, error)) error {
+	ticker := time.NewTicker(modemStatusPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return &PortError{code: OperationCanceled, wrapped: ctx.Err()}
+		case <-p.internal.closed:
+			return &PortError{code: PortClosed}
+		case <-ticker.C:
+		}
+
+		if ok, err := changed(); err != nil || ok {
+			return err
+		}
+	}
+}
+
+func (m ModemStatusMask) orAll() ModemStatusMask {
+	if m == 0 {
+		return ModemAll
+	}
+	return m
+}
+
+// diff returns the lines which differ.
+func (s *ModemStatusBits) diff(o *ModemStatusBits) ModemStatusMask {
+	var m ModemStatusMask
+	if s.CTS != o.CTS {
+		m |= ModemCTS
+	}
+	if s.DSR != o.DSR {
+		m |= ModemDSR
+	}
+	if s.RI != o.RI {
+		m |= ModemRI
+	}
+	if s.DCD != o.DCD {
+		m |= ModemDCD
+	}
+	return m
+}
@@ -0,0 +1,201 @@
+//go:build linux && !android
+
+package serial
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// openFDs returns the number of the open file descriptors of the process.
+func openFDs(t *testing.T) int {
+	t.Helper()
+
+	entries, err := os.ReadDir("/proc/self/fd")
+	require.NoError(t, err)
+	return len(entries)
+}
+
+// fakeModem replaces the modem ioctls for the pseudo terminal, the counters are not supported unless enabled.
+type fakeModem struct {
+	bits     atomic.Int32
+	cts      atomic.Uint32 // CTS transitions counter
+	counters bool
+
+	mu  sync.Mutex
+	rts []bool // RTS levels set
+}
+
+func newFakeModem(t *testing.T, counters bool) *fakeModem {
+	t.Helper()
+
+	m := &fakeModem{counters: counters}
+	prevBits, prevSetBits, prevCounters := ioctlModemBits, ioctlSetModemBits, ioctlCounters
+	t.Cleanup(func() { ioctlModemBits, ioctlSetModemBits, ioctlCounters = prevBits, prevSetBits, prevCounters })
+	ioctlModemBits = func(int) (int, error) { return int(m.bits.Load()), nil }
+	ioctlSetModemBits = func(_, status int) error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.bits.Store(int32(status))
+		m.rts = append(m.rts, status&unix.TIOCM_RTS != 0)
+		return nil
+	}
+	ioctlCounters = func(int) (Counters, error) {
+		if !m.counters {
+			return Counters{}, unix.ENOTTY
+		}
+		return Counters{CTS: m.cts.Load()}, nil
+	}
+	return m
+}
+
+// rtsHistory returns the RTS levels set so far and forgets them.
+func (m *fakeModem) rtsHistory() []bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h := m.rts
+	m.rts = nil
+	return h
+}
+
+func TestPort_WaitModemStatusChange(t *testing.T) {
+	for _, counters := range []bool{false, true} {
+		counters := counters
+		t.Run(fmt.Sprintf("counters=%v", counters), func(t *testing.T) {
+			modem := newFakeModem(t, counters)
+			_, name := openPTY(t)
+			p, err := Open(name)
+			require.NoError(t, err)
+			defer p.Close()
+
+			time.AfterFunc(20*time.Millisecond, func() {
+				modem.bits.Store(unix.TIOCM_CTS)
+				modem.cts.Add(1)
+			})
+			status, err := p.WaitModemStatusChange(context.Background(), ModemCTS)
+			require.NoError(t, err)
+			assert.True(t, status.CTS)
+
+			// Other lines are ignored
+			ctx, cancel := context.WithTimeout(context.Background(), 3*modemStatusPollInterval)
+			defer cancel()
+			time.AfterFunc(10*time.Millisecond, func() { modem.bits.Store(unix.TIOCM_CTS | unix.TIOCM_CD) })
+			_, err = p.WaitModemStatusChange(ctx, ModemCTS)
+			assert.ErrorIs(t, err, ErrCanceled)
+		})
+	}
+
+	// Short CTS pulse is seen by the counters only
+	modem := newFakeModem(t, true)
+	_, name := openPTY(t)
+	p, err := Open(name)
+	require.NoError(t, err)
+	defer p.Close()
+
+	time.AfterFunc(20*time.Millisecond, func() { modem.cts.Add(2) })
+	status, err := p.WaitModemStatusChange(context.Background(), ModemCTS)
+	require.NoError(t, err)
+	assert.False(t, status.CTS)
+}
+
+func TestPort_WaitModemStatusChange_CancelAndClose(t *testing.T) {
+	newFakeModem(t, true)
+	_, name := openPTY(t)
+	fds := openFDs(t)
+
+	p, err := Open(name, WithExclusive(ExclusiveFlock))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = p.WaitModemStatusChange(ctx, ModemAll)
+	require.ErrorIs(t, err, ErrCanceled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	res := make(chan error, 1)
+	go func() {
+		_, err := p.WaitModemStatusChange(context.Background(), ModemAll)
+		res <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, p.Close())
+	select {
+	case err := <-res:
+		assert.ErrorIs(t, err, ErrClosed)
+	case <-time.After(time.Second):
+		require.FailNow(t, "wait is not interrupted by Close")
+	}
+
+	// Nothing keeps the device open after Close
+	assert.Equal(t, fds, openFDs(t))
+	p, err = Open(name, WithExclusive(ExclusiveFlock))
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+}
+
+func TestPort_WatchModemStatus(t *testing.T) {
+	modem := newFakeModem(t, true)
+	_, name := openPTY(t)
+	p, err := Open(name)
+	require.NoError(t, err)
+	defer p.Close()
+
+	next := func(events <-chan ModemStatusEvent) (ModemStatusEvent, bool) {
+		select {
+		case e, ok := <-events:
+			return e, ok
+		case <-time.After(time.Second):
+			require.FailNow(t, "no event")
+		}
+		return ModemStatusEvent{}, false
+	}
+
+	// Canceled watch is closed with no error reported
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := p.WatchModemStatus(ctx, ModemAll)
+	require.NoError(t, err)
+	cancel()
+	_, ok := next(events)
+	assert.False(t, ok)
+
+	events, err = p.WatchModemStatus(context.Background(), ModemCTS)
+	require.NoError(t, err)
+	time.AfterFunc(20*time.Millisecond, func() {
+		modem.bits.Store(unix.TIOCM_DSR | unix.TIOCM_CTS)
+		modem.cts.Add(1)
+	})
+	e, ok := next(events)
+	require.True(t, ok)
+	require.NoError(t, e.Err)
+	assert.Equal(t, ModemStatusBits{CTS: true, DSR: true}, e.Status)
+	assert.Equal(t, ModemCTS, e.Changed)
+
+	// Short pulses are reported, including the one occurred while the previous event is not received yet
+	modem.cts.Add(2)
+	time.Sleep(3 * modemStatusPollInterval)
+	modem.cts.Add(2)
+	for i := 0; i < 2; i++ {
+		e, ok = next(events)
+		require.True(t, ok)
+		require.NoError(t, e.Err)
+		assert.Equal(t, ModemStatusBits{CTS: true, DSR: true}, e.Status)
+		assert.Equal(t, ModemCTS, e.Changed)
+	}
+
+	// The error is reported before the channel is closed
+	require.NoError(t, p.Close())
+	e, ok = next(events)
+	require.True(t, ok)
+	assert.ErrorIs(t, e.Err, ErrClosed)
+	_, ok = next(events)
+	assert.False(t, ok)
+}
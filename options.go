@@ -7,46 +7,114 @@
 
 package serial
 
-type Option func(p *Port)
+// Option modifies the port configuration, see Config.
+type Option func(c *Config)
 
 func WithBaudrate(o int) Option {
-	return func(p *Port) {
-		p.baudRate = o
+	return func(c *Config) {
+		c.BaudRate = o
 	}
 }
 
 func WithDataBits(o int) Option {
-	return func(p *Port) {
-		p.dataBits = o
+	return func(c *Config) {
+		c.DataBits = o
 	}
 }
 
 func WithParity(o Parity) Option {
-	return func(p *Port) {
-		p.parity = o
+	return func(c *Config) {
+		c.Parity = o
 	}
 }
 
 func WithStopBits(o StopBits) Option {
-	return func(p *Port) {
-		p.stopBits = o
+	return func(c *Config) {
+		c.StopBits = o
 	}
 }
 
 func WithReadTimeout(o int) Option {
-	return func(p *Port) {
-		p.setReadTimeoutValues(o)
+	return func(c *Config) {
+		c.ReadTimeout = o
 	}
 }
 
 func WithWriteTimeout(o int) Option {
-	return func(p *Port) {
-		p.setWriteTimeoutValues(o)
+	return func(c *Config) {
+		c.WriteTimeout = o
+	}
+}
+
+func WithFlowControl(o FlowControl) Option {
+	return func(c *Config) {
+		c.FlowControl = o
+	}
+}
+
+func WithSoftwareFlowControl(o SoftwareFlowControl) Option {
+	return func(c *Config) {
+		c.SoftwareFlowControl = o
+	}
+}
+
+func WithRS485(o RS485Config) Option {
+	return func(c *Config) {
+		c.RS485 = o
+	}
+}
+
+// WithLineErrorReporting enables reporting of the received break condition, parity and framing errors
+// via Port.ReadWithErrors() (not supported on windows). Plain Port.Read() still returns clean data only.
+func WithLineErrorReporting(o bool) Option {
+	return func(c *Config) {
+		c.LineErrorReporting = o
 	}
 }
 
 func WithHUPCL(o bool) Option {
-	return func(p *Port) {
-		p.hupcl = o
+	return func(c *Config) {
+		c.HUPCL = o
+	}
+}
+
+// WithExclusive sets the exclusive access mode (see ExclusiveMode type for more info).
+// Takes effect on open only.
+func WithExclusive(o ExclusiveMode) Option {
+	return func(c *Config) {
+		c.Exclusive = o
+	}
+}
+
+// WithUUCPLock enables the UUCP style lock files (e.g. /var/lock/LCK..ttyUSB0) used by minicom, cu, pppd
+// and others (unix only). The lock file is created on open with the current process PID and removed on close,
+// the stale lock files of the dead processes are removed. The port locked by another process is reported
+// as PortBusy wrapping LockHolder. Takes effect on open only.
+func WithUUCPLock(o bool) Option {
+	return func(c *Config) {
+		c.UUCPLock = o
+	}
+}
+
+// WithUUCPLockDir sets the directory of the UUCP lock files, DefaultUUCPLockDir is used if empty.
+func WithUUCPLockDir(o string) Option {
+	return func(c *Config) {
+		c.UUCPLockDir = o
+	}
+}
+
+// WithRestoreOnClose saves the device settings (termios or DCB) and the DTR and RTS lines status on open
+// and restores them on close, so the ports shared with the console or getty are left intact.
+// Takes effect on open only.
+func WithRestoreOnClose(o bool) Option {
+	return func(c *Config) {
+		c.RestoreOnClose = o
+	}
+}
+
+// WithConfig replaces the whole port configuration with c, the following options are applied on top of it.
+func WithConfig(o Config) Option {
+	return func(c *Config) {
+		*c = o
 	}
 }
@@ -0,0 +1,72 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+//go:build linux || darwin || freebsd || openbsd
+
+package serial
+
+const (
+	parmrkStateData = iota
+	parmrkStateFF   // 0xFF received
+	parmrkStateFF00 // 0xFF 0x00 received
+)
+
+// parmrkDecoder decodes the input marked by termios PARMRK flag:
+// 0xFF 0xFF is a data byte 0xFF, 0xFF 0x00 0x00 is a break and 0xFF 0x00 X is a character X with an error.
+// Escape sequence may be split between reads, so the decoder keeps its state.
+type parmrkDecoder struct {
+	state int
+}
+
+// decode decodes src into dst, which may be the same slice as src, and returns the number of data bytes.
+// Decoded events are appended to events (if not nil) with offsets shifted by base.
+// Errors other than break are reported as LineParityError if parity is enabled and as LineFramingError otherwise,
+// termios does not distinguish them.
+func (d *parmrkDecoder) decode(dst, src []byte, base int, parity bool, events *[]LineEvent) int {
+	n := 0
+	for _, c := range src {
+		switch d.state {
+		case parmrkStateFF:
+			switch c {
+			case 0xFF:
+				dst[n] = c
+				n++
+				d.state = parmrkStateData
+			case 0x00:
+				d.state = parmrkStateFF00
+			default: // must not happen, keep the character
+				dst[n] = c
+				n++
+				d.state = parmrkStateData
+			}
+		case parmrkStateFF00:
+			if events != nil {
+				e := LineEvent{Kind: LineFramingError, Offset: base + n, Char: c}
+				switch {
+				case c == 0:
+					e.Kind = LineBreak
+				case parity:
+					e.Kind = LineParityError
+				}
+				*events = append(*events, e)
+			}
+			d.state = parmrkStateData
+		default:
+			if c == 0xFF {
+				d.state = parmrkStateFF
+				continue
+			}
+			dst[n] = c
+			n++
+		}
+	}
+	return n
+}
+
+func (d *parmrkDecoder) reset() {
+	d.state = parmrkStateData
+}
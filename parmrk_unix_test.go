@@ -0,0 +1,70 @@
+//go:build linux || darwin || freebsd || openbsd
+
+package serial
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParmrkDecoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks [][]byte
+		parity bool
+		data   []byte
+		events []LineEvent
+	}{
+		{
+			name:   "plain",
+			chunks: [][]byte{{1, 2, 3}},
+			data:   []byte{1, 2, 3},
+		},
+		{
+			name:   "escaped 0xFF",
+			chunks: [][]byte{{1, 0xFF, 0xFF, 2}},
+			data:   []byte{1, 0xFF, 2},
+		},
+		{
+			name:   "break",
+			chunks: [][]byte{{1, 0xFF, 0x00, 0x00, 2}},
+			data:   []byte{1, 2},
+			events: []LineEvent{{Kind: LineBreak, Offset: 1}},
+		},
+		{
+			name:   "framing error",
+			chunks: [][]byte{{0xFF, 0x00, 'a', 'b'}},
+			data:   []byte{'b'},
+			events: []LineEvent{{Kind: LineFramingError, Offset: 0, Char: 'a'}},
+		},
+		{
+			name:   "parity error",
+			chunks: [][]byte{{'a', 0xFF, 0x00, 'b'}},
+			parity: true,
+			data:   []byte{'a'},
+			events: []LineEvent{{Kind: LineParityError, Offset: 1, Char: 'b'}},
+		},
+		{
+			name:   "split sequences",
+			chunks: [][]byte{{1, 0xFF}, {0x00}, {0x00, 2, 0xFF}, {0xFF, 3}},
+			data:   []byte{1, 2, 0xFF, 3},
+			events: []LineEvent{{Kind: LineBreak, Offset: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d parmrkDecoder
+			var data []byte
+			var events []LineEvent
+			for _, chunk := range tt.chunks {
+				buf := append([]byte(nil), chunk...)
+				n := d.decode(buf, buf, len(data), tt.parity, &events)
+				data = append(data, buf[:n]...)
+			}
+			assert.Equal(t, tt.data, data)
+			assert.Equal(t, tt.events, events)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+// PortDetails describes the serial port, see GetDetailedPortsList().
+// Only Name is filled on the platforms other than linux.
+type PortDetails struct {
+	Name  string   // Device path, e.g. /dev/ttyUSB0
+	IDs   []string // Persistent symlinks by the device identity, e.g. /dev/serial/by-id/usb-FTDI_FT232R_A1B2C3-if00-port0
+	Paths []string // Persistent symlinks by the physical location, e.g. /dev/serial/by-path/pci-0000:00:14.0-usb-0:2:1.0-port0
+
+	Driver string // Kernel driver, e.g. ftdi_sio, cdc_acm or serial
+	Bus    string // Bus type, e.g. usb, pci, pnp or platform, empty for the virtual ports with no device
+
+	IsUSB        bool
+	VID          string // USB vendor ID, 4 hex digits
+	PID          string // USB product ID, 4 hex digits
+	Manufacturer string
+	Product      string
+	SerialNumber string
+	Interface    int    // USB interface number
+	USBPath      string // Physical USB path, the bus number followed by the hub ports chain, e.g. 1-1.4
+}
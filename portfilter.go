@@ -0,0 +1,132 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// PortFilter selects the serial port by its identity instead of the device path, see OpenMatching().
+// Empty fields match any port, the USB fields never match the non-USB ports.
+type PortFilter struct {
+	VID          string // USB vendor ID, hex digits, case-insensitive
+	PID          string // USB product ID, hex digits, case-insensitive
+	SerialNumber string
+	Product      string // Product string pattern, see path.Match for the syntax, e.g. "FT232R*"
+	Interface    *int   // USB interface number, for the multi-port adapters and composite devices
+	USBPath      string // Physical USB path, e.g. 1-1.4
+}
+
+// Match reports whether the port details match the filter.
+func (f PortFilter) Match(d *PortDetails) bool {
+	if f.usb() && !d.IsUSB {
+		return false
+	}
+	if f.VID != "" && !strings.EqualFold(f.VID, d.VID) {
+		return false
+	}
+	if f.PID != "" && !strings.EqualFold(f.PID, d.PID) {
+		return false
+	}
+	if f.SerialNumber != "" && f.SerialNumber != d.SerialNumber {
+		return false
+	}
+	if f.Product != "" {
+		if ok, err := path.Match(f.Product, d.Product); err != nil || !ok {
+			return false
+		}
+	}
+	if f.Interface != nil && *f.Interface != d.Interface {
+		return false
+	}
+	if f.USBPath != "" && f.USBPath != d.USBPath {
+		return false
+	}
+	return true
+}
+
+func (f PortFilter) usb() bool {
+	return f.VID != "" || f.PID != "" || f.SerialNumber != "" || f.Product != "" || f.Interface != nil || f.USBPath != ""
+}
+
+// String returns the filter description used in the error messages, e.g. "vid=0403 pid=6001 serial=A1B2C3".
+func (f PortFilter) String() string {
+	var s []string
+	if f.VID != "" {
+		s = append(s, "vid="+f.VID)
+	}
+	if f.PID != "" {
+		s = append(s, "pid="+f.PID)
+	}
+	if f.SerialNumber != "" {
+		s = append(s, "serial="+f.SerialNumber)
+	}
+	if f.Product != "" {
+		s = append(s, fmt.Sprintf("product=%q", f.Product))
+	}
+	if f.Interface != nil {
+		s = append(s, fmt.Sprintf("interface=%d", *f.Interface))
+	}
+	if f.USBPath != "" {
+		s = append(s, "usb="+f.USBPath)
+	}
+	if len(s) == 0 {
+		return "any"
+	}
+	return strings.Join(s, " ")
+}
+
+// AmbiguousMatch lists the ports matching the filter passed to OpenMatching() when more than one port matches.
+// It is wrapped by the PortError with PortAmbiguous code.
+type AmbiguousMatch struct {
+	Candidates []*PortDetails
+}
+
+func (m *AmbiguousMatch) Error() string {
+	s := make([]string, len(m.Candidates))
+	for i, d := range m.Candidates {
+		s[i] = d.Name
+		if d.IsUSB {
+			s[i] += fmt.Sprintf(" (%s:%s %s, usb %s, interface %d)", d.VID, d.PID, d.SerialNumber, d.USBPath, d.Interface)
+		}
+	}
+	return "candidates: " + strings.Join(s, ", ")
+}
+
+// OpenMatching opens the only serial port matching the filter, see GetDetailedPortsList() for the supported details.
+// PortNotFound error is returned if no port matches and PortAmbiguous wrapping AmbiguousMatch if more than one does.
+func OpenMatching(filter PortFilter, opts ...Option) (*Port, error) {
+	d, err := findMatching(filter)
+	if err != nil {
+		return nil, withOp(err, "open", filter.String())
+	}
+	return Open(d.Name, opts...)
+}
+
+// findMatching returns the only port matching the filter.
+func findMatching(filter PortFilter) (*PortDetails, error) {
+	ports, err := GetDetailedPortsList()
+	if err != nil {
+		return nil, err
+	}
+	var found []*PortDetails
+	for _, d := range ports {
+		if filter.Match(d) {
+			found = append(found, d)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, &PortError{code: PortNotFound}
+	case 1:
+		return found[0], nil
+	}
+	return nil, &PortError{code: PortAmbiguous, wrapped: &AmbiguousMatch{Candidates: found}}
+}
@@ -0,0 +1,58 @@
+package serial
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortFilter_Match(t *testing.T) {
+	ftdi := &PortDetails{
+		Name:         "/dev/ttyUSB0",
+		IsUSB:        true,
+		VID:          "0403",
+		PID:          "6001",
+		Product:      "FT232R USB UART",
+		SerialNumber: "A1B2C3",
+		USBPath:      "1-2",
+	}
+	uart := &PortDetails{Name: "/dev/ttyS0", Driver: "serial", Bus: "pnp"}
+	one, zero := 1, 0
+
+	tests := []struct {
+		filter PortFilter
+		ftdi   bool
+		uart   bool
+	}{
+		{filter: PortFilter{}, ftdi: true, uart: true},
+		{filter: PortFilter{VID: "0403"}, ftdi: true},
+		{filter: PortFilter{VID: "0403", PID: "6001"}, ftdi: true},
+		{filter: PortFilter{VID: "0403", PID: "6015"}},
+		{filter: PortFilter{VID: "10C4"}},
+		{filter: PortFilter{SerialNumber: "A1B2C3"}, ftdi: true},
+		{filter: PortFilter{SerialNumber: "a1b2c3"}},
+		{filter: PortFilter{Product: "FT232R*"}, ftdi: true},
+		{filter: PortFilter{Product: "*UART"}, ftdi: true},
+		{filter: PortFilter{Product: "FT232R"}},
+		{filter: PortFilter{Product: "["}},
+		{filter: PortFilter{Interface: &zero}, ftdi: true},
+		{filter: PortFilter{Interface: &one}},
+		{filter: PortFilter{USBPath: "1-2"}, ftdi: true},
+		{filter: PortFilter{USBPath: "1-2.1"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.ftdi, tt.filter.Match(ftdi), "%v matches %s", tt.filter, ftdi.Name)
+		assert.Equal(t, tt.uart, tt.filter.Match(uart), "%v matches %s", tt.filter, uart.Name)
+	}
+
+	// VID and PID are case-insensitive
+	assert.True(t, PortFilter{VID: "10c4", PID: "ea60"}.Match(&PortDetails{IsUSB: true, VID: "10C4", PID: "EA60"}))
+}
+
+func TestPortFilter_String(t *testing.T) {
+	one := 1
+	assert.Equal(t, "any", PortFilter{}.String())
+	assert.Equal(t, "vid=0403 pid=6001 serial=A1B2C3", PortFilter{VID: "0403", PID: "6001", SerialNumber: "A1B2C3"}.String())
+	assert.Equal(t, `product="FT232R*" interface=1 usb=1-2`, PortFilter{Product: "FT232R*", Interface: &one, USBPath: "1-2"}.String())
+}
@@ -0,0 +1,327 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConnState is the connection state of the ReconnectingPort.
+type ConnState int
+
+const (
+	// StateConnected the port is open.
+	StateConnected ConnState = iota + 1
+	// StateDisconnected the connection has been lost, the port is being reopened.
+	StateDisconnected
+	// StateClosed the ReconnectingPort has been closed.
+	StateClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("ConnState(%d)", s)
+}
+
+const (
+	// DefaultMinBackoff is the default delay before the first reopen attempt, see ReconnectOptions.
+	DefaultMinBackoff = 100 * time.Millisecond
+	// DefaultMaxBackoff is the default limit of the delay between reopen attempts, see ReconnectOptions.
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// ReconnectOptions configures the ReconnectingPort.
+type ReconnectOptions struct {
+	// Delay before the first reopen attempt, doubled after every failed attempt up to MaxBackoff.
+	// DefaultMinBackoff and DefaultMaxBackoff are used if zero.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Read and Write return ErrDisconnected while the port is being reopened instead of blocking until reconnect.
+	FailFast bool
+	// OnStateChange is called on every state change and on every failed reopen attempt (StateDisconnected
+	// with the attempt error). It is called synchronously, so it must not block or call ReconnectingPort.Close().
+	OnStateChange func(state ConnState, err error)
+}
+
+func (o ReconnectOptions) backoff() (time.Duration, time.Duration) {
+	minBackoff, maxBackoff := o.MinBackoff, o.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = DefaultMinBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
+	return minBackoff, maxBackoff
+}
+
+// ReconnectingPort is the serial port reopened transparently with the same options after the device is gone
+// (an operation fails with ErrDisconnected), e.g. the USB adapter is unplugged and plugged in again.
+// It is safe for one concurrent reader, one concurrent writer and Close.
+type ReconnectingPort struct {
+	name string
+	open func() (*Port, error)
+	opts ReconnectOptions
+
+	notifyMu sync.Mutex // keeps OnStateChange calls ordered
+	mu       sync.Mutex
+	port     *Port         // nil while disconnected
+	ready    chan struct{} // closed when connected
+	lastErr  error         // the cause of the disconnect or the last reopen attempt error
+	closed   bool
+	done     chan struct{} // closed by Close
+	wg       sync.WaitGroup
+}
+
+// OpenReconnecting opens the port by name with the given options, see ReconnectingPort.
+// The error of the first open is returned as is, no reconnect is attempted.
+func OpenReconnecting(name string, ro ReconnectOptions, opts ...Option) (*ReconnectingPort, error) {
+	return openReconnecting(name, func() (*Port, error) { return Open(name, opts...) }, ro)
+}
+
+// OpenReconnectingMatching opens the port matching the filter with the given options, see OpenMatching()
+// and ReconnectingPort. The port is looked up again on every reopen attempt, so it may get another name.
+// The error of the first open is returned as is, no reconnect is attempted.
+func OpenReconnectingMatching(filter PortFilter, ro ReconnectOptions, opts ...Option) (*ReconnectingPort, error) {
+	return openReconnecting(filter.String(), func() (*Port, error) { return OpenMatching(filter, opts...) }, ro)
+}
+
+func openReconnecting(name string, open func() (*Port, error), ro ReconnectOptions) (*ReconnectingPort, error) {
+	p, err := open()
+	if err != nil {
+		return nil, err
+	}
+	ready := make(chan struct{})
+	close(ready)
+	return &ReconnectingPort{
+		name:  name,
+		open:  open,
+		opts:  ro,
+		port:  p,
+		ready: ready,
+		done:  make(chan struct{}),
+	}, nil
+}
+
+func (r *ReconnectingPort) String() string {
+	return r.name
+}
+
+// State returns the current connection state.
+func (r *ReconnectingPort) State() ConnState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.closed:
+		return StateClosed
+	case r.port == nil:
+		return StateDisconnected
+	}
+	return StateConnected
+}
+
+// Port returns the currently open port, e.g. for the control calls, nil while disconnected.
+// The port must not be closed, it is closed by ReconnectingPort on disconnect.
+// The control calls failed with ErrDisconnected do not start the reopening, the next Read or Write does.
+func (r *ReconnectingPort) Port() *Port {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.port
+}
+
+// Read reads from the port, see Port.Read.
+func (r *ReconnectingPort) Read(b []byte) (int, error) {
+	return r.ReadContext(context.Background(), b)
+}
+
+// ReadContext reads from the port, see Port.ReadContext. The data received before the disconnect is returned
+// without an error.
+func (r *ReconnectingPort) ReadContext(ctx context.Context, b []byte) (int, error) {
+	for {
+		p, err := r.connected(ctx)
+		if err != nil {
+			return 0, withOp(err, "read", r.name)
+		}
+		n, err := p.ReadContext(ctx, b)
+		if !r.lost(p, err) {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Write writes to the port, see Port.Write.
+func (r *ReconnectingPort) Write(b []byte) (int, error) {
+	return r.WriteContext(context.Background(), b)
+}
+
+// WriteContext writes to the port, see Port.WriteContext. The data is written again after reconnect only if
+// nothing has been written before the disconnect, otherwise the error is returned with the number of bytes written.
+func (r *ReconnectingPort) WriteContext(ctx context.Context, b []byte) (int, error) {
+	for {
+		p, err := r.connected(ctx)
+		if err != nil {
+			return 0, withOp(err, "write", r.name)
+		}
+		n, err := p.WriteContext(ctx, b)
+		if !r.lost(p, err) || n > 0 {
+			return n, err
+		}
+	}
+}
+
+// Close closes the port and stops reopening it, the pending operations are interrupted.
+func (r *ReconnectingPort) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return &PortError{code: PortClosed, op: "close", port: r.name}
+	}
+	r.closed = true
+	close(r.done)
+	p := r.port
+	r.port = nil
+	r.mu.Unlock()
+
+	var err error
+	if p != nil {
+		err = p.Close()
+	}
+	r.wg.Wait()
+	r.notify(StateClosed, nil)
+	return err
+}
+
+// connected returns the open port, waits for reconnect unless FailFast is set.
+func (r *ReconnectingPort) connected(ctx context.Context) (*Port, error) {
+	for {
+		r.mu.Lock()
+		p, ready, lastErr, closed := r.port, r.ready, r.lastErr, r.closed
+		r.mu.Unlock()
+
+		switch {
+		case closed:
+			return nil, &PortError{code: PortClosed}
+		case p != nil:
+			return p, nil
+		case r.opts.FailFast:
+			// lastErr is PortError already, its cause is wrapped to keep the error code single
+			cause := errors.Unwrap(lastErr)
+			if cause == nil {
+				cause = lastErr
+			}
+			return nil, &PortError{code: PortDisconnected, wrapped: cause}
+		}
+
+		select {
+		case <-ready:
+		case <-r.done:
+		case <-ctx.Done():
+			return nil, &PortError{code: OperationCanceled, wrapped: ctx.Err()}
+		}
+	}
+}
+
+// lost reports whether err means that the port p is gone, the reopening is started then.
+func (r *ReconnectingPort) lost(p *Port, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return false
+	}
+	if r.port != p {
+		// Another operation has already detected the disconnect and closed p
+		return errors.Is(err, ErrClosed) || errors.Is(err, ErrDisconnected)
+	}
+	if !errors.Is(err, ErrDisconnected) {
+		return false
+	}
+
+	r.port = nil
+	r.ready = make(chan struct{})
+	r.lastErr = err
+	r.wg.Add(1)
+	go r.reconnect(p, err)
+	return true
+}
+
+// reconnect closes the lost port and reopens it with the exponential backoff until succeeded or closed.
+func (r *ReconnectingPort) reconnect(lost *Port, cause error) {
+	defer r.wg.Done()
+
+	_ = lost.Close() // the device is gone, the error is not interesting
+	r.notify(StateDisconnected, cause)
+
+	backoff, maxBackoff := r.opts.backoff()
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-t.C:
+		}
+
+		p, err := r.open()
+		if err == nil {
+			r.mu.Lock()
+			if r.closed {
+				r.mu.Unlock()
+				_ = p.Close()
+				return
+			}
+			r.port, r.lastErr = p, nil
+			close(r.ready)
+			r.mu.Unlock()
+			r.notify(StateConnected, nil)
+			return
+		}
+
+		r.mu.Lock()
+		r.lastErr = err
+		r.mu.Unlock()
+		r.notify(StateDisconnected, err)
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		t.Reset(backoff)
+	}
+}
+
+func (r *ReconnectingPort) notify(state ConnState, err error) {
+	if r.opts.OnStateChange == nil {
+		return
+	}
+	r.notifyMu.Lock()
+	defer r.notifyMu.Unlock()
+
+	r.opts.OnStateChange(state, err)
+}
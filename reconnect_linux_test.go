@@ -0,0 +1,195 @@
+//go:build linux && !android
+
+package serial_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/albenik/go-serial/v2"
+)
+
+// relinkPTY opens the new pseudo-terminal and points the link to its slave side like udev does on replug.
+func relinkPTY(t *testing.T, link string) *os.File {
+	t.Helper()
+
+	m, name := openPTY(t)
+	tmp := link + ".tmp"
+	require.NoError(t, os.Symlink(name, tmp))
+	require.NoError(t, os.Rename(tmp, link))
+	return m
+}
+
+type stateChange struct {
+	state serial.ConnState
+	err   error
+}
+
+func openReconnectingPTY(t *testing.T, ro serial.ReconnectOptions) (*os.File, string, *serial.ReconnectingPort, <-chan stateChange) {
+	t.Helper()
+
+	link := filepath.Join(t.TempDir(), "ttyLINK0")
+	m := relinkPTY(t, link)
+
+	states := make(chan stateChange, 100)
+	ro.MinBackoff, ro.MaxBackoff = 5*time.Millisecond, 20*time.Millisecond
+	ro.OnStateChange = func(state serial.ConnState, err error) { states <- stateChange{state: state, err: err} }
+	r, err := serial.OpenReconnecting(link, ro, serial.WithReadTimeout(-1))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+
+	return m, link, r, states
+}
+
+// waitState returns the errors reported before the state.
+func waitState(t *testing.T, states <-chan stateChange, want serial.ConnState) []error {
+	t.Helper()
+
+	var errs []error
+	for {
+		select {
+		case s := <-states:
+			if s.state == want {
+				return errs
+			}
+			errs = append(errs, s.err)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no state change", "want %v", want)
+		}
+	}
+}
+
+func TestReconnectingPort(t *testing.T) {
+	m, link, r, states := openReconnectingPTY(t, serial.ReconnectOptions{})
+	assert.Equal(t, serial.StateConnected, r.State())
+	assert.Equal(t, "connected", r.State().String())
+	assert.Equal(t, link, r.String())
+
+	buf := make([]byte, 16)
+	_, err := m.Write([]byte("hello"))
+	require.NoError(t, err)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+
+	// Unplugged, the read blocks until replugged
+	result := make(chan string)
+	go func() {
+		n, err := r.Read(buf)
+		assert.NoError(t, err)
+		result <- string(buf[:n])
+	}()
+	require.NoError(t, m.Close())
+	errs := waitState(t, states, serial.StateDisconnected)
+	assert.Empty(t, errs)
+	assert.Equal(t, serial.StateDisconnected, r.State())
+	assert.Nil(t, r.Port())
+
+	// The link is stale until replugged, reopen attempts fail
+	require.NoError(t, os.Remove(link))
+	time.Sleep(50 * time.Millisecond)
+	m = relinkPTY(t, link)
+	errs = waitState(t, states, serial.StateConnected)
+	require.NotEmpty(t, errs)
+	for _, err := range errs {
+		assert.ErrorIs(t, err, serial.ErrPortNotFound)
+	}
+	assert.Equal(t, serial.StateConnected, r.State())
+	assert.NotNil(t, r.Port())
+
+	_, err = m.Write([]byte("world"))
+	require.NoError(t, err)
+	select {
+	case s := <-result:
+		assert.Equal(t, "world", s)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "read is not resumed")
+	}
+
+	// Write is resumed as well
+	require.NoError(t, m.Close())
+	written := make(chan error)
+	go func() {
+		_, err := r.Write([]byte("again"))
+		written <- err
+	}()
+	waitState(t, states, serial.StateDisconnected)
+	m = relinkPTY(t, link)
+	waitState(t, states, serial.StateConnected)
+	require.NoError(t, <-written)
+	n, err = m.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "again", string(buf[:n]))
+
+	require.NoError(t, r.Close())
+	waitState(t, states, serial.StateClosed)
+	assert.Equal(t, serial.StateClosed, r.State())
+	_, err = r.Read(buf)
+	assert.ErrorIs(t, err, serial.ErrClosed)
+	assert.ErrorIs(t, r.Close(), serial.ErrClosed)
+}
+
+func TestReconnectingPort_FailFast(t *testing.T) {
+	m, link, r, states := openReconnectingPTY(t, serial.ReconnectOptions{FailFast: true})
+
+	require.NoError(t, os.Remove(link))
+	require.NoError(t, m.Close())
+
+	_, err := r.Read(make([]byte, 16))
+	assert.ErrorIs(t, err, serial.ErrDisconnected)
+	waitState(t, states, serial.StateDisconnected)
+
+	// Fails immediately while disconnected, the cause of the last reopen attempt error is wrapped
+	waitState(t, states, serial.StateDisconnected) // failed attempt
+	_, err = r.Write([]byte("hello"))
+	assert.ErrorIs(t, err, serial.ErrDisconnected)
+	var portErr *serial.PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, "write", portErr.Op())
+	assert.Equal(t, link, portErr.PortName())
+	assert.EqualError(t, err, "write "+link+": port disconnected: no such file or directory")
+
+	m = relinkPTY(t, link)
+	waitState(t, states, serial.StateConnected)
+	_, err = r.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 16)
+	n, err := m.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+}
+
+func TestReconnectingPort_CloseWhileDisconnected(t *testing.T) {
+	m, link, r, states := openReconnectingPTY(t, serial.ReconnectOptions{})
+
+	require.NoError(t, os.Remove(link))
+	require.NoError(t, m.Close())
+
+	result := make(chan error)
+	go func() {
+		_, err := r.Read(make([]byte, 16))
+		result <- err
+	}()
+	waitState(t, states, serial.StateDisconnected)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := r.WriteContext(ctx, []byte("hello"))
+	assert.ErrorIs(t, err, serial.ErrCanceled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, r.Close())
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, serial.ErrClosed)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "read is not interrupted")
+	}
+	waitState(t, states, serial.StateClosed)
+}
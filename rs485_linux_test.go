@@ -0,0 +1,95 @@
+//go:build linux && !android
+
+package serial
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPort_RS485Software(t *testing.T) {
+	modem := newFakeModem(t, false)
+	m, name := openPTY(t)
+	rs485 := RS485Config{Enabled: true, RTSOnSend: true, DelayRTSBeforeSend: 5, DelayRTSAfterSend: 5}
+	p, err := Open(name, WithRS485(rs485))
+	require.NoError(t, err)
+	defer p.Close()
+
+	// The pseudo terminal has no kernel RS-485 support
+	require.True(t, p.internal.rs485Soft)
+	c, err := p.RS485Config()
+	require.NoError(t, err)
+	assert.Equal(t, rs485, c)
+	assert.Equal(t, []bool{false}, modem.rtsHistory())
+
+	// The reply received while sending is kept
+	_, err = m.Write([]byte("reply"))
+	require.NoError(t, err)
+	n, err := p.Write([]byte("request"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+	assert.Equal(t, []bool{true, false}, modem.rtsHistory())
+
+	buf := make([]byte, 7)
+	_, err = io.ReadFull(m, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "request", string(buf))
+	require.NoError(t, p.SetReadTimeout(100))
+	n, err = p.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "reply", string(buf[:n]))
+}
+
+func TestPort_RS485Software_Cancel(t *testing.T) {
+	modem := newFakeModem(t, false)
+	_, name := openPTY(t)
+	p, err := Open(name, WithRS485(RS485Config{Enabled: true, RTSOnSend: true, DelayRTSBeforeSend: 10000}))
+	require.NoError(t, err)
+	defer p.Close()
+	modem.rtsHistory()
+
+	// The delays are interrupted, RTS is restored
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	n, err := p.WriteContext(ctx, []byte("request"))
+	require.ErrorIs(t, err, ErrCanceled)
+	assert.Zero(t, n)
+	assert.Equal(t, []bool{true, false}, modem.rtsHistory())
+
+	res := make(chan error, 1)
+	go func() {
+		_, err := p.Write([]byte("request"))
+		res <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, p.Close())
+	select {
+	case err := <-res:
+		assert.ErrorIs(t, err, ErrClosed)
+	case <-time.After(time.Second):
+		require.FailNow(t, "write is not interrupted by Close")
+	}
+}
+
+func TestPort_RS485Software_RTSCTS(t *testing.T) {
+	newFakeModem(t, false)
+	_, name := openPTY(t)
+	rs485 := WithRS485(RS485Config{Enabled: true, RTSOnSend: true})
+
+	// The emulation drives RTS, so the hardware flow control is rejected
+	_, err := Open(name, rs485, WithFlowControl(RTSCTSFlowControl))
+	assert.ErrorIs(t, err, ErrInvalidFlowControl)
+
+	p, err := Open(name, rs485)
+	require.NoError(t, err)
+	defer p.Close()
+	assert.ErrorIs(t, p.Reconfigure(WithFlowControl(RTSCTSFlowControl)), ErrInvalidFlowControl)
+	c, err := p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, NoFlowControl, c.FlowControl)
+}
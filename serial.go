@@ -9,7 +9,10 @@
 package serial
 
 import (
+	"context"
 	"os"
+	"sync"
+	"time"
 )
 
 //go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zsyscall_windows.go syscall_windows.go
@@ -36,12 +39,80 @@ const (
 	TwoStopBits
 )
 
+const (
+	// NoFlowControl disables hardware flow control (default).
+	NoFlowControl FlowControl = iota
+	// RTSCTSFlowControl enables RTS/CTS hardware flow control.
+	RTSCTSFlowControl
+	// DTRDSRFlowControl enables DTR/DSR hardware flow control (not supported on linux and openbsd).
+	DTRDSRFlowControl
+)
+
+const (
+	// DefaultXON is the default software flow control start character (DC1).
+	DefaultXON byte = 0x11
+	// DefaultXOFF is the default software flow control stop character (DC3).
+	DefaultXOFF byte = 0x13
+)
+
 // StopBits describe a serial port stop bits setting.
 type StopBits int
 
 // Parity describes a serial port parity setting.
 type Parity int
 
+// FlowControl describes a serial port hardware flow control setting.
+type FlowControl int
+
+// SoftwareFlowControl describes a serial port software (XON/XOFF) flow control setting.
+type SoftwareFlowControl struct {
+	// Send XOFF/XON to the remote side to throttle the input (IXOFF).
+	Input bool `json:"input,omitempty" yaml:"input,omitempty" toml:"input,omitempty"`
+	// Suspend the output on XOFF until XON received (IXON).
+	Output bool `json:"output,omitempty" yaml:"output,omitempty" toml:"output,omitempty"`
+	// Any received character resumes the suspended output (IXANY, not supported on windows).
+	Any bool `json:"any,omitempty" yaml:"any,omitempty" toml:"any,omitempty"`
+	// Start character, DefaultXON is used if zero.
+	XON byte `json:"xon,omitempty" yaml:"xon,omitempty" toml:"xon,omitempty"`
+	// Stop character, DefaultXOFF is used if zero. Must differ from the start character if Input or Output is set.
+	XOFF byte `json:"xoff,omitempty" yaml:"xoff,omitempty" toml:"xoff,omitempty"`
+}
+
+func (c SoftwareFlowControl) xon() byte {
+	if c.XON == 0 {
+		return DefaultXON
+	}
+	return c.XON
+}
+
+func (c SoftwareFlowControl) xoff() byte {
+	if c.XOFF == 0 {
+		return DefaultXOFF
+	}
+	return c.XOFF
+}
+
+// RS485Config describes a serial port RS-485 half-duplex mode setting (see struct serial_rs485 in linux).
+// Kernel RS-485 mode is used on linux if supported by the driver, otherwise RTS line is driven by software
+// around every write, so RTSCTSFlowControl is rejected. On windows RS-485 mode is implemented with RTS_CONTROL_TOGGLE,
+// only Enabled field is used.
+type RS485Config struct {
+	// Enable RS-485 mode.
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	// Logical level of the RTS line while sending.
+	RTSOnSend bool `json:"rts_on_send,omitempty" yaml:"rts_on_send,omitempty" toml:"rts_on_send,omitempty"`
+	// Logical level of the RTS line after sending.
+	RTSAfterSend bool `json:"rts_after_send,omitempty" yaml:"rts_after_send,omitempty" toml:"rts_after_send,omitempty"`
+	// Delay after RTS set and before send in milliseconds.
+	DelayRTSBeforeSend int `json:"delay_rts_before_send,omitempty" yaml:"delay_rts_before_send,omitempty" toml:"delay_rts_before_send,omitempty"` //nolint:lll
+	// Delay after send and before RTS set in milliseconds.
+	DelayRTSAfterSend int `json:"delay_rts_after_send,omitempty" yaml:"delay_rts_after_send,omitempty" toml:"delay_rts_after_send,omitempty"` //nolint:lll
+	// Receive data while sending (kernel mode only, the software emulation never disables the receiver).
+	RxDuringTx bool `json:"rx_during_tx,omitempty" yaml:"rx_during_tx,omitempty" toml:"rx_during_tx,omitempty"`
+	// Enable bus termination (hardware mode only, if supported).
+	TerminateBus bool `json:"terminate_bus,omitempty" yaml:"terminate_bus,omitempty" toml:"terminate_bus,omitempty"`
+}
+
 // ModemStatusBits contains all the modem status bits for a serial port (CTS, DSR, etc...).
 // It can be retrieved with the Port.GetModemStatusBits() method.
 type ModemStatusBits struct {
@@ -52,18 +123,60 @@ type ModemStatusBits struct {
 }
 
 // Port is the interface for a serial Port.
+// Port is safe for one concurrent reader, one concurrent writer and any number of control calls.
+// Close may be called concurrently too, it interrupts the pending operations and waits for them to return.
 type Port struct {
-	name     string
-	opened   bool
-	baudRate int      // The serial port bitrate (aka Baudrate)
-	dataBits int      // Size of the character (must be 5, 6, 7 or 8)
-	parity   Parity   // Parity (see Parity type for more info)
-	stopBits StopBits // Stop bits (see StopBits type for more info)
-	hupcl    bool     // Lower DTR line on close (hang up)
+	name  string
+	state portState
+	mu    sync.Mutex // guards cfg and the os specific settings applied from it
+	cfg   Config     // Requested configuration (see Config type for more info)
 
 	internal *port // os specific (implementation like os.File)
 }
 
+// portState tracks the operations in progress, so the handle is released by Close only after all of them returned.
+type portState struct {
+	mu     sync.Mutex
+	closed bool
+	active int           // operations in progress
+	idle   chan struct{} // closed when the port is closed and no operations are in progress
+}
+
+// begin registers new operation, reports false if the port is closed.
+func (s *portState) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.active++
+	return true
+}
+
+func (s *portState) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.closed && s.active == 0 {
+		close(s.idle)
+	}
+}
+
+// close marks the state closed, reports false if it is already closed.
+func (s *portState) close() (<-chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, false
+	}
+	s.closed = true
+	s.idle = make(chan struct{})
+	if s.active == 0 {
+		close(s.idle)
+	}
+	return s.idle, true
+}
+
 func (p *Port) String() string {
 	if p == nil {
 		return "Error: <nil> port instance"
@@ -71,25 +184,94 @@ func (p *Port) String() string {
 	return p.name
 }
 
-func (p *Port) checkValid() error {
-	if p == nil || p.internal == nil || !isHandleValid(p.internal.handle) {
+// Open opens the serial port with the default configuration modified by the given options.
+func Open(name string, opts ...Option) (*Port, error) {
+	return OpenWithConfig(name, NewConfig(opts...))
+}
+
+// SetDeadline sets both the read and write deadlines, see SetReadDeadline and SetWriteDeadline.
+func (p *Port) SetDeadline(t time.Time) error {
+	if err := p.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return p.SetWriteDeadline(t)
+}
+
+// SendBreak sends the break condition for the duration d, see SetBreak.
+// Close interrupts the waiting, the break condition is cleared anyway.
+func (p *Port) SendBreak(d time.Duration) (err error) {
+	defer p.wrapErr("send break", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	if err := p.setBreak(true); err != nil {
+		return err
+	}
+	err = p.sleep(context.Background(), d)
+	if cerr := p.setBreak(false); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// sleep waits for d, the waiting is interrupted when ctx is done or the port is closed.
+func (p *Port) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return &PortError{code: OperationCanceled, wrapped: ctx.Err()}
+	case <-p.internal.closed:
+		return &PortError{code: PortClosed}
+	}
+}
+
+// acquire checks the port is open and keeps it open until release() called.
+// Nested calls are allowed, so the exported methods may call each other.
+func (p *Port) acquire() error {
+	if p == nil || p.internal == nil {
 		return &PortError{code: PortClosed, wrapped: os.ErrInvalid}
 	}
-	if !p.opened {
+	if !p.state.begin() {
 		return &PortError{code: PortClosed}
 	}
+	if !isHandleValid(p.internal.handle) {
+		p.state.end()
+		return &PortError{code: PortClosed, wrapped: os.ErrInvalid}
+	}
 	return nil
 }
 
+func (p *Port) release() {
+	p.state.end()
+}
+
+// markClosed makes all the following operations fail with PortClosed.
+// Returned channel is closed when the operations in progress returned.
+func (p *Port) markClosed() (<-chan struct{}, error) {
+	if p == nil || p.internal == nil {
+		return nil, &PortError{code: PortClosed, wrapped: os.ErrInvalid}
+	}
+	idle, ok := p.state.close()
+	if !ok {
+		return nil, &PortError{code: PortClosed}
+	}
+	return idle, nil
+}
+
 func newWithDefaults(n string, p *port) *Port {
 	return &Port{
 		name:     n,
-		opened:   true,
-		baudRate: 9600,
-		dataBits: 8,
-		parity:   NoParity,
-		stopBits: OneStopBit,
-		hupcl:    false,
+		cfg:      DefaultConfig(),
 		internal: p,
 	}
 }
@@ -0,0 +1,722 @@
+//go:build linux && !android
+
+package serial_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/albenik/go-serial/v2"
+)
+
+// openPTY opens a pseudo-terminal pair and returns the master side and the slave device name,
+// it is shared with the internal tests.
+var openPTY = serial.OpenPTY
+
+func openPTYPort(t *testing.T, opts ...serial.Option) (*os.File, *serial.Port) {
+	t.Helper()
+
+	m, name := openPTY(t)
+	p, err := serial.Open(name, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = p.Close() })
+
+	return m, p
+}
+
+func TestPort_ReadContext(t *testing.T) {
+	m, p := openPTYPort(t, serial.WithReadTimeout(-1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	n, err := p.ReadContext(ctx, make([]byte, 16))
+	assert.Zero(t, n)
+	var portErr *serial.PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, serial.OperationCanceled, portErr.Code())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The port stays usable after cancellation
+	_, err = m.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 16)
+	n, err = p.ReadContext(context.Background(), buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+}
+
+func TestPort_SetReadDeadline(t *testing.T) {
+	_, p := openPTYPort(t, serial.WithReadTimeout(-1))
+
+	// Deadline set concurrently takes effect on the pending Read
+	time.AfterFunc(20*time.Millisecond, func() {
+		assert.NoError(t, p.SetReadDeadline(time.Now().Add(20*time.Millisecond)))
+	})
+
+	n, err := p.Read(make([]byte, 16))
+	assert.Zero(t, n)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.True(t, os.IsTimeout(err))
+
+	// Already exceeded deadline fails immediately
+	_, err = p.Read(make([]byte, 16))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// Zero value resets the deadline
+	require.NoError(t, p.SetReadDeadline(time.Time{}))
+	require.NoError(t, p.SetReadTimeout(10))
+	n, err = p.Read(make([]byte, 16))
+	assert.Zero(t, n)
+	assert.NoError(t, err)
+}
+
+func TestPort_FlowControl(t *testing.T) {
+	_, p := openPTYPort(t, serial.WithFlowControl(serial.RTSCTSFlowControl))
+
+	fc, err := p.FlowControl()
+	require.NoError(t, err)
+	assert.Equal(t, serial.RTSCTSFlowControl, fc)
+
+	require.NoError(t, p.Reconfigure(serial.WithFlowControl(serial.NoFlowControl)))
+	fc, err = p.FlowControl()
+	require.NoError(t, err)
+	assert.Equal(t, serial.NoFlowControl, fc)
+
+	var portErr *serial.PortError
+	require.ErrorAs(t, p.Reconfigure(serial.WithFlowControl(serial.DTRDSRFlowControl)), &portErr)
+	assert.Equal(t, serial.InvalidFlowControl, portErr.Code())
+}
+
+func TestPort_Drain(t *testing.T) {
+	m, p := openPTYPort(t)
+
+	_, err := p.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, p.Drain())
+
+	buf := make([]byte, 16)
+	n, err := m.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, p.DrainContext(ctx), context.Canceled)
+}
+
+func TestPort_SendBreak(t *testing.T) {
+	_, p := openPTYPort(t)
+	assert.NoError(t, p.SendBreak(10*time.Millisecond))
+
+	// Close interrupts the break
+	closed := make(chan error, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		closed <- p.Close()
+	}()
+	start := time.Now()
+	assert.ErrorIs(t, p.SendBreak(time.Minute), serial.ErrClosed)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.NoError(t, <-closed)
+}
+
+func TestPort_ReadWithErrors(t *testing.T) {
+	m, p := openPTYPort(t, serial.WithLineErrorReporting(true), serial.WithReadTimeout(100))
+
+	_, err := m.Write([]byte{1, 0xFF, 2})
+	require.NoError(t, err)
+
+	buf := make([]byte, 3)
+	n, events, err := p.ReadWithErrors(buf)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 0xFF, 2}, buf[:n])
+	assert.Empty(t, events)
+}
+
+func TestPort_Config(t *testing.T) {
+	_, p := openPTYPort(t,
+		serial.WithBaudrate(115200),
+		serial.WithDataBits(7),
+		serial.WithParity(serial.EvenParity),
+		serial.WithStopBits(serial.TwoStopBits),
+		serial.WithFlowControl(serial.RTSCTSFlowControl),
+		serial.WithSoftwareFlowControl(serial.SoftwareFlowControl{Input: true, XON: 0x01}),
+		serial.WithReadTimeout(100),
+		serial.WithHUPCL(true),
+	)
+
+	// PTY driver always forces 8 bits without parity, so the effective config differs from the requested one
+	c, err := p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, &serial.Config{
+		BaudRate:    115200,
+		DataBits:    8,
+		Parity:      serial.NoParity,
+		StopBits:    serial.TwoStopBits,
+		FlowControl: serial.RTSCTSFlowControl,
+		SoftwareFlowControl: serial.SoftwareFlowControl{
+			Input: true,
+			XON:   0x01,
+			XOFF:  serial.DefaultXOFF,
+		},
+		ReadTimeout: 100,
+		HUPCL:       true,
+	}, c)
+
+	// Non-standard baudrate
+	require.NoError(t, p.Reconfigure(serial.WithBaudrate(250000)))
+	c, err = p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, 250000, c.BaudRate)
+}
+
+func TestPort_Reconfigure_SoftwareFlowControl(t *testing.T) {
+	_, p := openPTYPort(t)
+
+	tests := []serial.SoftwareFlowControl{
+		{Input: true, Output: true, Any: true, XON: 0x01, XOFF: 0x02},
+		{Output: true, XON: serial.DefaultXON, XOFF: serial.DefaultXOFF},
+		{Input: true, XON: 0x11, XOFF: 0x7F},
+		{XON: serial.DefaultXON, XOFF: serial.DefaultXOFF},
+	}
+	for _, fc := range tests {
+		require.NoError(t, p.Reconfigure(serial.WithSoftwareFlowControl(fc)))
+		c, err := p.Config()
+		require.NoError(t, err)
+		assert.Equal(t, fc, c.SoftwareFlowControl)
+	}
+
+	// Rejected config is not applied
+	err := p.Reconfigure(serial.WithSoftwareFlowControl(serial.SoftwareFlowControl{Output: true, XON: 0x01, XOFF: 0x01}))
+	require.ErrorIs(t, err, serial.ErrInvalidFlowControl)
+	c, err := p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, tests[len(tests)-1], c.SoftwareFlowControl)
+}
+
+func TestPort_Reconfigure_OpenOnly(t *testing.T) {
+	_, p := openPTYPort(t, serial.WithExclusive(serial.ExclusiveFlock), serial.WithRestoreOnClose(true))
+
+	tests := []serial.Option{
+		serial.WithExclusive(serial.ExclusiveNone),
+		serial.WithUUCPLock(true),
+		serial.WithUUCPLockDir(t.TempDir()),
+		serial.WithRestoreOnClose(false),
+	}
+	for _, o := range tests {
+		err := p.Reconfigure(o)
+		require.ErrorIs(t, err, serial.ErrNotImplemented)
+		assert.Contains(t, err.Error(), "change after open")
+	}
+
+	// The settings in effect are reported and may be applied back
+	c, err := p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, serial.ExclusiveFlock, c.Exclusive)
+	assert.True(t, c.RestoreOnClose)
+	require.NoError(t, p.ApplyConfig(*c))
+
+	c.Exclusive = serial.ExclusiveBoth
+	assert.ErrorIs(t, p.ApplyConfig(*c), serial.ErrNotImplemented)
+}
+
+func TestOpenWithConfig(t *testing.T) {
+	_, name := openPTY(t)
+
+	c := serial.DefaultConfig()
+	c.BaudRate = 57600
+	c.StopBits = serial.TwoStopBits
+	p, err := serial.OpenWithConfig(name, c)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = p.Close() })
+
+	got, err := p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, 57600, got.BaudRate)
+	assert.Equal(t, serial.TwoStopBits, got.StopBits)
+
+	c.BaudRate = 19200
+	c.FlowControl = serial.RTSCTSFlowControl
+	require.NoError(t, p.ApplyConfig(c))
+
+	// Options are applied on top of the applied config
+	require.NoError(t, p.Reconfigure(serial.WithStopBits(serial.OneStopBit)))
+	got, err = p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, 19200, got.BaudRate)
+	assert.Equal(t, serial.RTSCTSFlowControl, got.FlowControl)
+	assert.Equal(t, serial.OneStopBit, got.StopBits)
+}
+
+func TestOpenWithConfig_Invalid(t *testing.T) {
+	_, name := openPTY(t)
+
+	c := serial.DefaultConfig()
+	c.DataBits = 9
+	_, err := serial.OpenWithConfig(name, c)
+	var portErr *serial.PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, serial.InvalidDataBits, portErr.Code())
+}
+
+func TestConfig_Validate_Linux(t *testing.T) {
+	tests := []struct {
+		name   string
+		option serial.Option
+		code   serial.PortErrorCode
+	}{
+		{name: "OnePointFiveStopBits", option: serial.WithStopBits(serial.OnePointFiveStopBits), code: serial.InvalidStopBits},
+		{name: "DTRDSRFlowControl", option: serial.WithFlowControl(serial.DTRDSRFlowControl), code: serial.InvalidFlowControl},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var portErr *serial.PortError
+			require.ErrorAs(t, serial.NewConfig(tt.option).Validate(), &portErr)
+			assert.Equal(t, tt.code, portErr.Code())
+		})
+	}
+
+	// Non-standard baud rates are set via BOTHER
+	assert.NoError(t, serial.NewConfig(serial.WithBaudrate(250000)).Validate())
+}
+
+func TestPort_Reconfigure_Invalid(t *testing.T) {
+	_, p := openPTYPort(t, serial.WithBaudrate(57600))
+
+	err := p.Reconfigure(
+		serial.WithBaudrate(115200),
+		serial.WithDataBits(4),
+		serial.WithStopBits(serial.OnePointFiveStopBits),
+	)
+	var portErr *serial.PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, serial.InvalidDataBits, portErr.Code())
+	assert.Contains(t, err.Error(), "StopBits 1.5")
+
+	// Nothing applied, neither to the device nor to the port state
+	c, err := p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, 57600, c.BaudRate)
+
+	require.NoError(t, p.Reconfigure(serial.WithHUPCL(true)))
+	c, err = p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, 57600, c.BaudRate)
+	assert.Equal(t, serial.OneStopBit, c.StopBits)
+}
+
+func TestPort_RestoreOnClose(t *testing.T) {
+	m, name := openPTY(t)
+
+	// Terminal settings of the slave side are available via master side
+	termios := func() *unix.Termios {
+		tios, err := unix.IoctlGetTermios(int(m.Fd()), unix.TCGETS)
+		require.NoError(t, err)
+		return tios
+	}
+
+	// Non-standard baudrate is set via BOTHER and has to be restored via TCSETS2
+	p, err := serial.Open(name, serial.WithBaudrate(250000), serial.WithFlowControl(serial.RTSCTSFlowControl))
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+	orig := termios()
+	require.NotZero(t, orig.Cflag&unix.BOTHER)
+
+	p, err = serial.Open(name, serial.WithBaudrate(9600), serial.WithRestoreOnClose(true))
+	require.NoError(t, err)
+	c, err := p.Config()
+	require.NoError(t, err)
+	assert.Equal(t, 9600, c.BaudRate)
+	assert.Equal(t, serial.NoFlowControl, c.FlowControl)
+	assert.True(t, c.RestoreOnClose)
+	require.NoError(t, p.Close())
+
+	assert.Equal(t, orig, termios())
+}
+
+func TestPort_NoRestoreOnClose(t *testing.T) {
+	m, name := openPTY(t)
+
+	orig, err := unix.IoctlGetTermios(int(m.Fd()), unix.TCGETS)
+	require.NoError(t, err)
+	require.NotZero(t, orig.Lflag&unix.ICANON)
+
+	p, err := serial.Open(name)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+
+	// Raw mode is left by default
+	tios, err := unix.IoctlGetTermios(int(m.Fd()), unix.TCGETS)
+	require.NoError(t, err)
+	assert.Zero(t, tios.Lflag&unix.ICANON)
+}
+
+func TestOpen_ExclusiveFlock(t *testing.T) {
+	_, name := openPTY(t)
+
+	p, err := serial.Open(name, serial.WithExclusive(serial.ExclusiveFlock))
+	require.NoError(t, err)
+
+	_, err = serial.Open(name, serial.WithExclusive(serial.ExclusiveBoth))
+	var portErr *serial.PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, serial.PortBusy, portErr.Code())
+	var holder *serial.LockHolder
+	require.ErrorAs(t, err, &holder)
+	assert.Equal(t, "flock", holder.Lock)
+	assert.Equal(t, os.Getpid(), holder.PID)
+
+	// Advisory lock does not prevent the non-cooperating processes
+	sniffer, err := serial.Open(name, serial.WithExclusive(serial.ExclusiveNone))
+	require.NoError(t, err)
+	require.NoError(t, sniffer.Close())
+
+	require.NoError(t, p.Close())
+	p, err = serial.Open(name, serial.WithExclusive(serial.ExclusiveFlock))
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+}
+
+func TestOpen_ExclusiveBothBusy(t *testing.T) {
+	_, name := openPTY(t)
+
+	p, err := serial.Open(name, serial.WithExclusive(serial.ExclusiveBoth))
+	require.NoError(t, err)
+	defer p.Close()
+
+	// Fails on flock even for root (TIOCEXCL does not apply), the owner's TIOCEXCL must be kept
+	_, err = serial.Open(name, serial.WithExclusive(serial.ExclusiveBoth))
+	require.ErrorIs(t, err, serial.ErrPortBusy)
+
+	_, err = serial.Open(name, serial.WithExclusive(serial.ExclusiveBoth))
+	require.ErrorIs(t, err, serial.ErrPortBusy)
+
+	f, err := os.OpenFile(name, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		assert.ErrorIs(t, err, unix.EBUSY) // not root
+		return
+	}
+	defer f.Close()
+	excl, err := unix.IoctlGetInt(int(f.Fd()), unix.TIOCGEXCL)
+	require.NoError(t, err)
+	assert.Equal(t, 1, excl)
+}
+
+func TestOpen_UUCPLock(t *testing.T) {
+	_, name := openPTY(t)
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, "LCK..pts_"+filepath.Base(name))
+
+	p, err := serial.Open(name, serial.WithUUCPLock(true), serial.WithUUCPLockDir(dir))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(lockFile)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%10d\n", os.Getpid()), string(data))
+
+	_, err = serial.Open(name, serial.WithUUCPLock(true), serial.WithUUCPLockDir(dir))
+	var portErr *serial.PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, serial.PortBusy, portErr.Code())
+	var holder *serial.LockHolder
+	require.ErrorAs(t, err, &holder)
+	assert.Equal(t, serial.LockHolder{PID: os.Getpid(), Lock: "uucp"}, *holder)
+
+	require.NoError(t, p.Close())
+	assert.NoFileExists(t, lockFile)
+}
+
+func TestOpen_UUCPLock_Stale(t *testing.T) {
+	_, name := openPTY(t)
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, "LCK..pts_"+filepath.Base(name))
+
+	for _, stale := range []string{
+		fmt.Sprintf("%10d\n", 99999999), // PID above the pid_max limit is never alive
+		"garbage\n",
+		"",
+	} {
+		require.NoError(t, os.WriteFile(lockFile, []byte(stale), 0o600))
+
+		p, err := serial.Open(name, serial.WithUUCPLock(true), serial.WithUUCPLockDir(dir))
+		require.NoError(t, err, "stale lock %q", stale)
+
+		data, err := os.ReadFile(lockFile)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%10d\n", os.Getpid()), string(data))
+		require.NoError(t, p.Close())
+	}
+}
+
+func TestOpen_UUCPLock_OpenFailed(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := serial.Open("/dev/nonexistent-serial-port", serial.WithUUCPLock(true), serial.WithUUCPLockDir(dir))
+	require.Error(t, err)
+
+	// Lock is released if the port could not be opened
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestPort_CloseUnblocksRead(t *testing.T) {
+	_, p := openPTYPort(t, serial.WithReadTimeout(-1))
+
+	res := make(chan error, 1)
+	go func() {
+		_, err := p.Read(make([]byte, 16))
+		res <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, p.Close())
+
+	select {
+	case err := <-res:
+		var portErr *serial.PortError
+		require.ErrorAs(t, err, &portErr)
+		assert.Equal(t, serial.PortClosed, portErr.Code())
+	case <-time.After(time.Second):
+		t.Fatal("Read is not unblocked by Close")
+	}
+}
+
+func TestPort_CloseUnblocksWrite(t *testing.T) {
+	_, p := openPTYPort(t, serial.WithWriteTimeout(0))
+
+	// Nobody reads the master side, so the write blocks once the pty buffer is full
+	data := make([]byte, 1<<20)
+	res := make(chan error, 1)
+	go func() {
+		_, err := p.Write(data)
+		res <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, p.Close())
+
+	select {
+	case err := <-res:
+		var portErr *serial.PortError
+		require.ErrorAs(t, err, &portErr)
+		assert.Equal(t, serial.PortClosed, portErr.Code())
+	case <-time.After(time.Second):
+		t.Fatal("Write is not unblocked by Close")
+	}
+}
+
+func TestPort_WriteTimeout(t *testing.T) {
+	_, p := openPTYPort(t, serial.WithWriteTimeout(50))
+
+	// Nobody reads the master side, so the write times out once the pty buffer is full.
+	// The expired timeout is not an error, the number of bytes written is returned.
+	data := make([]byte, 1<<20)
+	n, err := p.Write(data)
+	require.NoError(t, err)
+	assert.Positive(t, n)
+	assert.Less(t, n, len(data))
+
+	// The expired deadline is an error
+	require.NoError(t, p.SetWriteDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err = p.Write(data)
+	assert.ErrorIs(t, err, serial.ErrTimeout)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestPort_ConcurrentClose(t *testing.T) {
+	_, p := openPTYPort(t)
+
+	const n = 8
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- p.Close()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var closed int
+	for err := range errs {
+		if err == nil {
+			closed++
+			continue
+		}
+		var portErr *serial.PortError
+		require.ErrorAs(t, err, &portErr)
+		assert.Equal(t, serial.PortClosed, portErr.Code())
+	}
+	assert.Equal(t, 1, closed)
+}
+
+// TestPort_Concurrent is meant to be run with the race detector.
+func TestPort_Concurrent(t *testing.T) {
+	m, p := openPTYPort(t, serial.WithReadTimeout(-1))
+
+	// Echo everything back on the master side
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := m.Read(buf)
+			if err != nil {
+				return
+			}
+			if _, err = m.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	readErr, writeErr := make(chan error, 1), make(chan error, 1)
+	stop := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		buf := make([]byte, 64)
+		for {
+			if _, err := p.Read(buf); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			if _, err := p.Write([]byte("ping")); err != nil {
+				writeErr <- err
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// Control calls, errors are expected after the port is closed
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_ = p.SetReadDeadline(time.Time{})
+				_ = p.SetWriteTimeout(i * 100)
+				_ = p.Reconfigure(serial.WithBaudrate([]int{9600, 19200, 57600, 115200}[i]))
+				_, _ = p.Config()
+				_, _ = p.ReadyToRead()
+				_ = p.ResetOutputBuffer()
+			}
+		}(i)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	require.NoError(t, p.Close())
+	close(stop)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("operations are not unblocked by Close")
+	}
+
+	for _, err := range []error{<-readErr, <-writeErr} {
+		var portErr *serial.PortError
+		require.ErrorAs(t, err, &portErr)
+		assert.Equal(t, serial.PortClosed, portErr.Code())
+	}
+}
+
+func TestPort_Disconnected(t *testing.T) {
+	m, p := openPTYPort(t, serial.WithReadTimeout(-1))
+
+	// Closing the master side hangs up the line like the unplugged USB adapter does
+	require.NoError(t, m.Close())
+
+	_, err := p.Read(make([]byte, 16))
+	var portErr *serial.PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, serial.PortDisconnected, portErr.Code())
+	assert.ErrorIs(t, err, serial.ErrDisconnected)
+
+	_, err = p.Write([]byte("hello"))
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, serial.PortDisconnected, portErr.Code())
+	assert.ErrorIs(t, err, serial.ErrDisconnected)
+}
+
+func TestOpen_Error(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		sentinel error
+	}{
+		{name: filepath.Join(dir, "ttyNotFound0"), sentinel: serial.ErrPortNotFound},
+		{name: dir, sentinel: serial.ErrInvalidSerialPort},
+		{name: "/dev/null", sentinel: serial.ErrInvalidSerialPort},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := serial.Open(tt.name)
+			assert.Nil(t, p)
+			assert.ErrorIs(t, err, tt.sentinel)
+
+			var portErr *serial.PortError
+			require.ErrorAs(t, err, &portErr)
+			assert.Equal(t, "open", portErr.Op())
+			assert.Equal(t, tt.name, portErr.PortName())
+			assert.Contains(t, err.Error(), "open "+tt.name+": ")
+		})
+	}
+}
+
+func TestPort_ErrorOp(t *testing.T) {
+	m, name := openPTY(t)
+	p, err := serial.Open(name)
+	require.NoError(t, err)
+
+	require.NoError(t, p.SetReadDeadline(time.Now()))
+	_, err = p.Read(make([]byte, 16))
+	assert.ErrorIs(t, err, serial.ErrTimeout)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.EqualError(t, err, "read "+name+": i/o deadline exceeded: i/o timeout")
+
+	require.NoError(t, m.Close())
+	_, err = p.Write([]byte("hello"))
+	assert.ErrorIs(t, err, serial.ErrDisconnected)
+	assert.EqualError(t, err, "write "+name+": port disconnected: input/output error")
+
+	assert.ErrorIs(t, p.Close(), serial.ErrDisconnected) // TIOCNXCL fails on the hung up line
+	err = p.Close()
+	assert.ErrorIs(t, err, serial.ErrClosed)
+	assert.EqualError(t, err, "close "+name+": port has been closed")
+}
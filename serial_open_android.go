@@ -14,7 +14,11 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func accquireExclusiveAccess(_ int) error {
+func accquireExclusiveAccess(_ int) (bool, error) {
+	return false, nil
+}
+
+func (p *Port) releaseExclusiveAccess() error {
 	return nil
 }
 
@@ -23,7 +27,9 @@ func (p *Port) closeAndReturnError(code PortErrorCode, err error) *PortError {
 		code: code,
 		wrapped: multierr.Combine(
 			err,
+			p.restoreState(),
 			unix.Close(p.internal.handle),
+			p.internal.uucp.release(),
 		),
 	}
 }
@@ -14,8 +14,19 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func accquireExclusiveAccess(h int) error {
-	return unix.IoctlSetInt(h, unix.TIOCEXCL, 0)
+// accquireExclusiveAccess sets TIOCEXCL on the device, reports whether it is set.
+func accquireExclusiveAccess(h int) (bool, error) {
+	if err := unix.IoctlSetInt(h, unix.TIOCEXCL, 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *Port) releaseExclusiveAccess() error {
+	if !p.internal.tiocexcl {
+		return nil
+	}
+	return unix.IoctlSetInt(p.internal.handle, unix.TIOCNXCL, 0)
 }
 
 func (p *Port) closeAndReturnError(code PortErrorCode, err error) *PortError {
@@ -23,8 +34,10 @@ func (p *Port) closeAndReturnError(code PortErrorCode, err error) *PortError {
 		code: code,
 		wrapped: multierr.Combine(
 			err,
-			unix.IoctlSetInt(p.internal.handle, unix.TIOCNXCL, 0),
+			p.restoreState(),
+			p.releaseExclusiveAccess(),
 			unix.Close(p.internal.handle),
+			p.internal.uucp.release(),
 		),
 	}
 }
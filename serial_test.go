@@ -1,8 +1,10 @@
 package serial_test
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -16,6 +18,7 @@ func TestPortNilReceiver_Error(t *testing.T) {
 		require.ErrorAs(t, err, &portErr)
 		assert.Equal(t, serial.PortClosed, portErr.Code())
 		assert.ErrorIs(t, err, os.ErrInvalid)
+		assert.NotErrorIs(t, err, serial.ErrDisconnected)
 	}
 
 	t.Run("Close", func(t *testing.T) {
@@ -26,6 +29,10 @@ func TestPortNilReceiver_Error(t *testing.T) {
 		checkError((*serial.Port)(nil).Reconfigure())
 	})
 
+	t.Run("ApplyConfig", func(t *testing.T) {
+		checkError((*serial.Port)(nil).ApplyConfig(serial.DefaultConfig()))
+	})
+
 	t.Run("ReadyToRead", func(t *testing.T) {
 		_, err := (*serial.Port)(nil).ReadyToRead()
 		checkError(err)
@@ -36,11 +43,34 @@ func TestPortNilReceiver_Error(t *testing.T) {
 		checkError(err)
 	})
 
+	t.Run("ReadContext", func(t *testing.T) {
+		_, err := (*serial.Port)(nil).ReadContext(context.Background(), make([]byte, 16))
+		checkError(err)
+	})
+
+	t.Run("ReadWithErrors", func(t *testing.T) {
+		_, _, err := (*serial.Port)(nil).ReadWithErrors(make([]byte, 16))
+		checkError(err)
+	})
+
 	t.Run("Write", func(t *testing.T) {
 		_, err := (*serial.Port)(nil).Write([]byte{1, 2, 3, 4, 5, 6, 7, 8})
 		checkError(err)
 	})
 
+	t.Run("WriteContext", func(t *testing.T) {
+		_, err := (*serial.Port)(nil).WriteContext(context.Background(), []byte{1, 2, 3, 4, 5, 6, 7, 8})
+		checkError(err)
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		checkError((*serial.Port)(nil).Drain())
+	})
+
+	t.Run("DrainContext", func(t *testing.T) {
+		checkError((*serial.Port)(nil).DrainContext(context.Background()))
+	})
+
 	t.Run("ResetInputBuffer", func(t *testing.T) {
 		checkError((*serial.Port)(nil).ResetInputBuffer())
 	})
@@ -57,6 +87,14 @@ func TestPortNilReceiver_Error(t *testing.T) {
 		checkError((*serial.Port)(nil).SetRTS(false))
 	})
 
+	t.Run("SetBreak", func(t *testing.T) {
+		checkError((*serial.Port)(nil).SetBreak(true))
+	})
+
+	t.Run("SendBreak", func(t *testing.T) {
+		checkError((*serial.Port)(nil).SendBreak(time.Millisecond))
+	})
+
 	t.Run("SetReadTimeout", func(t *testing.T) {
 		checkError((*serial.Port)(nil).SetReadTimeout(1))
 	})
@@ -73,10 +111,52 @@ func TestPortNilReceiver_Error(t *testing.T) {
 		checkError((*serial.Port)(nil).SetWriteTimeout(1))
 	})
 
+	t.Run("SetDeadline", func(t *testing.T) {
+		checkError((*serial.Port)(nil).SetDeadline(time.Now()))
+	})
+
+	t.Run("SetReadDeadline", func(t *testing.T) {
+		checkError((*serial.Port)(nil).SetReadDeadline(time.Now()))
+	})
+
+	t.Run("SetWriteDeadline", func(t *testing.T) {
+		checkError((*serial.Port)(nil).SetWriteDeadline(time.Now()))
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		_, err := (*serial.Port)(nil).Config()
+		checkError(err)
+	})
+
+	t.Run("FlowControl", func(t *testing.T) {
+		_, err := (*serial.Port)(nil).FlowControl()
+		checkError(err)
+	})
+
+	t.Run("RS485Config", func(t *testing.T) {
+		_, err := (*serial.Port)(nil).RS485Config()
+		checkError(err)
+	})
+
 	t.Run("GetModemStatusBits", func(t *testing.T) {
 		_, err := (*serial.Port)(nil).GetModemStatusBits()
 		checkError(err)
 	})
+
+	t.Run("Counters", func(t *testing.T) {
+		_, err := (*serial.Port)(nil).Counters()
+		checkError(err)
+	})
+
+	t.Run("WaitModemStatusChange", func(t *testing.T) {
+		_, err := (*serial.Port)(nil).WaitModemStatusChange(context.Background(), serial.ModemAll)
+		checkError(err)
+	})
+
+	t.Run("WatchModemStatus", func(t *testing.T) {
+		_, err := (*serial.Port)(nil).WatchModemStatus(context.Background(), serial.ModemAll)
+		checkError(err)
+	})
 }
 
 func TestPortTestPortNilReceiver_String(t *testing.T) {
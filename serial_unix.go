@@ -10,11 +10,9 @@
 package serial
 
 import (
+	"context"
 	"errors"
-	"os"
-	"path"
-	"regexp"
-	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -26,11 +24,13 @@ import (
 
 const FIONREAD = 0x541B
 
-var (
-	zeroByte   = []byte{0}
-	portNameRx = regexp.MustCompile(regexFilter)
+const (
+	defaultReadTimeout  = 0 // Return immediately with the data available (if any)
+	defaultWriteTimeout = 0 // Block until all the data written
 )
 
+var zeroByte = []byte{0}
+
 type port struct {
 	handle int
 
@@ -38,71 +38,141 @@ type port struct {
 	readTimeout      int
 	writeTimeout     int
 
+	tiocexcl bool      // TIOCEXCL is set on the device
+	uucp     *uucpLock // UUCP lock file (if requested)
+
+	rs485Kernel bool // Kernel RS-485 mode is enabled
+	rs485Soft   bool // RS-485 mode is emulated by software
+
+	parmrk parmrkDecoder // Line errors decoder
+
+	modemMu sync.Mutex // serializes modem lines read-modify-write
+
 	closePipeR int
 	closePipeW int
+
+	readDeadline  deadline
+	writeDeadline deadline
+
+	readWake  wakePipe
+	writeWake wakePipe
+
+	closed chan struct{} // closed on port close
+
+	saved *savedState // device state to restore on close (if requested)
 }
 
-func Open(name string, opts ...Option) (*Port, error) {
-	h, err := unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NDELAY, 0)
-	if err != nil {
-		switch {
-		case errors.Is(err, unix.EBUSY):
-			return nil, &PortError{code: PortBusy}
-		case errors.Is(err, unix.EACCES):
-			return nil, &PortError{code: PermissionDenied}
-		default:
+// savedState is the device state saved on open to be restored on close, see WithRestoreOnClose.
+type savedState struct {
+	settings  *settings
+	modemBits int  // DTR and RTS lines status
+	modem     bool // modem lines are supported by the device
+}
+
+// OpenWithConfig opens the serial port with the configuration c, which is validated before the port opened.
+func OpenWithConfig(name string, c Config) (_ *Port, err error) {
+	defer func() { err = withOp(err, "open", name) }()
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	var lock *uucpLock
+	if c.UUCPLock {
+		if lock, err = acquireUUCPLock(c.UUCPLockDir, name); err != nil {
 			return nil, err
 		}
 	}
 
-	// does nothing in build for android
-	if err = accquireExclusiveAccess(h); err != nil {
-		return nil, newPortOSError(multierr.Append(err, unix.Close(h)))
+	h, err := unix.Open(name, unix.O_RDWR|unix.O_NOCTTY|unix.O_NDELAY, 0)
+	if err != nil {
+		return nil, multierr.Append(openError(err), lock.release())
 	}
 
 	p := newWithDefaults(name, &port{
 		handle:           h,
+		uucp:             lock,
 		firstByteTimeout: true,
 		readTimeout:      0,
 		writeTimeout:     0,
+		closed:           make(chan struct{}),
 	})
+	p.cfg = p.cfg.withOpenOnly(c)
 
-	// Setup serial port
-	if err := p.Reconfigure(opts...); err != nil {
-		return nil, p.closeAndReturnError(InvalidSerialPort, err)
+	if err := p.lockExclusive(c.Exclusive); err != nil {
+		return nil, p.closeAndReturnError(err.code, err.wrapped)
 	}
 
-	if err = unix.SetNonblock(h, false); err != nil {
-		return nil, p.closeAndReturnError(OsError, err)
+	if c.RestoreOnClose {
+		if p.internal.saved, err = p.saveState(); err != nil {
+			return nil, p.closeAndReturnError(InvalidSerialPort, err)
+		}
+	}
+
+	// Setup serial port
+	if err := p.ApplyConfig(c); err != nil {
+		return nil, p.closeAndReturnError(InvalidSerialPort, err)
 	}
 
+	// The port is kept in non-blocking mode, all blocking is done via select,
+	// so any pending Read or Write may be interrupted.
 	fds := []int{0, 0}
 	if err := syscall.Pipe(fds); err != nil {
-		_ = p.Close()
 		return nil, p.closeAndReturnError(OsError, err)
 	}
 	p.internal.closePipeR = fds[0]
 	p.internal.closePipeW = fds[1]
 
+	if p.internal.readWake, err = newWakePipe(); err != nil {
+		return nil, p.closeAndReturnError(OsError, multierr.Append(err, p.internal.closePipes()))
+	}
+	if p.internal.writeWake, err = newWakePipe(); err != nil {
+		return nil, p.closeAndReturnError(OsError, multierr.Combine(err, p.internal.readWake.close(), p.internal.closePipes()))
+	}
+
 	return p, nil
 }
 
-func (p *Port) Close() error {
-	// NOT thread safe
-	if err := p.checkValid(); err != nil {
+// openError maps the error returned by open(2) to PortError.
+func openError(err error) error {
+	switch {
+	case errors.Is(err, unix.EBUSY), errors.Is(err, unix.EWOULDBLOCK):
+		return &PortError{code: PortBusy, wrapped: err}
+	case errors.Is(err, unix.EACCES), errors.Is(err, unix.EPERM), errors.Is(err, unix.EROFS):
+		return &PortError{code: PermissionDenied, wrapped: err}
+	case errors.Is(err, unix.ENOENT), errors.Is(err, unix.ENXIO), errors.Is(err, unix.ENODEV):
+		return &PortError{code: PortNotFound, wrapped: err}
+	case errors.Is(err, unix.ENOTDIR), errors.Is(err, unix.EISDIR), errors.Is(err, unix.ENOTTY):
+		return &PortError{code: InvalidSerialPort, wrapped: err}
+	default:
+		return newPortOSError(err)
+	}
+}
+
+// Close closes the port. The pending operations are interrupted with PortClosed error,
+// the device is released after all of them returned.
+func (p *Port) Close() (err error) {
+	defer p.wrapErr("close", &err)
+
+	idle, err := p.markClosed()
+	if err != nil {
 		return err
 	}
+	close(p.internal.closed)
 
-	p.opened = false
+	// Send close signal to all pending reads and writes (if any) and wait for them
+	_, err = unix.Write(p.internal.closePipeW, zeroByte)
+	<-idle
 
-	// Send close signal to all pending reads (if any) and close signaling pipe
-	_, err := unix.Write(p.internal.closePipeW, zeroByte)
 	err = multierr.Combine(
 		err,
-		unix.Close(p.internal.closePipeW),
-		unix.Close(p.internal.closePipeR),
-		unix.IoctlSetInt(p.internal.handle, unix.TIOCNXCL, 0),
+		p.internal.closePipes(),
+		p.internal.readWake.close(),
+		p.internal.writeWake.close(),
+		p.restoreState(),
+		p.releaseExclusiveAccess(),
 		unix.Close(p.internal.handle),
+		p.internal.uucp.release(),
 	)
 
 	if err != nil {
@@ -111,17 +181,13 @@ func (p *Port) Close() error {
 	return nil
 }
 
-func (p *Port) Reconfigure(opts ...Option) error {
-	for _, o := range opts {
-		o(p)
-	}
-	return p.reconfigure()
-}
+func (p *Port) ReadyToRead() (_ uint32, err error) {
+	defer p.wrapErr("ready to read", &err)
 
-func (p *Port) ReadyToRead() (uint32, error) {
-	if err := p.checkValid(); err != nil {
+	if err := p.acquire(); err != nil {
 		return 0, err
 	}
+	defer p.release()
 
 	n, err := unix.IoctlGetInt(p.internal.handle, FIONREAD)
 	if err != nil {
@@ -131,19 +197,66 @@ func (p *Port) ReadyToRead() (uint32, error) {
 }
 
 func (p *Port) Read(b []byte) (int, error) {
-	if err := p.checkValid(); err != nil {
+	return p.ReadContext(context.Background(), b)
+}
+
+// ReadContext works like Read, but the pending operation is interrupted when ctx is done.
+// In that case the bytes read so far are returned along with a PortError wrapping ctx.Err(),
+// the port itself stays usable.
+func (p *Port) ReadContext(ctx context.Context, b []byte) (_ int, err error) {
+	defer p.wrapErr("read", &err)
+
+	if err := p.acquire(); err != nil {
 		return 0, err
 	}
+	defer p.release()
+	if err := ctx.Err(); err != nil {
+		return 0, &PortError{code: OperationCanceled, wrapped: err}
+	}
+
+	return p.read(ctx, b, nil)
+}
+
+// ReadWithErrors works like Read, but also returns the break conditions and line errors received
+// within the data, if enabled by WithLineErrorReporting() option.
+func (p *Port) ReadWithErrors(b []byte) (_ int, _ []LineEvent, err error) {
+	defer p.wrapErr("read", &err)
+
+	if err := p.acquire(); err != nil {
+		return 0, nil, err
+	}
+	defer p.release()
+
+	var events []LineEvent
+	n, err := p.read(context.Background(), b, &events)
+	return n, events, err
+}
+
+func (p *Port) read(ctx context.Context, b []byte, events *[]LineEvent) (int, error) {
+	stop := p.internal.readWake.wakeOnDone(ctx)
+	defer stop()
 
 	size, read := len(b), 0
-	fds := unixutils.NewFDSet(p.internal.handle, p.internal.closePipeR)
+	fds := unixutils.NewFDSet(p.internal.handle, p.internal.closePipeR, p.internal.readWake.r)
 	buf := make([]byte, size)
 
-	now := time.Now()
-	deadline := now.Add(time.Duration(p.internal.readTimeout) * time.Millisecond)
+	p.mu.Lock()
+	readTimeout, firstByteTimeout := p.internal.readTimeout, p.internal.firstByteTimeout
+	lineErrors := p.cfg.LineErrorReporting
+	p.mu.Unlock()
+
+	var deadline time.Time // zero value means no timeout
+	if readTimeout >= 0 {
+		deadline = time.Now().Add(time.Duration(readTimeout) * time.Millisecond)
+	}
 
 	for read < size {
-		res, err := unixutils.Select(fds, nil, fds, deadline.Sub(now))
+		if p.internal.readDeadline.expired() {
+			return read, newDeadlineExceededError()
+		}
+
+		timeout := minTimeout(timeoutUntil(deadline), timeoutUntil(p.internal.readDeadline.get()))
+		res, err := unixutils.Select(fds, nil, fds, timeout)
 		if err != nil {
 			if errors.Is(err, unix.EINTR) {
 				continue
@@ -154,28 +267,51 @@ func (p *Port) Read(b []byte) (int, error) {
 		if res.IsReadable(p.internal.closePipeR) {
 			return read, &PortError{code: PortClosed}
 		}
+		if res.IsReadable(p.internal.readWake.r) {
+			p.internal.readWake.drain()
+			if err = ctx.Err(); err != nil {
+				return read, &PortError{code: OperationCanceled, wrapped: err}
+			}
+			continue
+		}
 		if !res.IsReadable(p.internal.handle) {
+			if p.internal.readDeadline.expired() {
+				continue // reported at the beginning of the loop
+			}
 			return read, nil
 		}
 
 		n, err := unix.Read(p.internal.handle, buf[read:])
 		if err != nil {
-			if errors.Is(err, unix.EINTR) {
+			if errors.Is(err, unix.EINTR) || errors.Is(err, unix.EAGAIN) {
 				continue
 			}
 			return read, newPortOSError(err)
 		}
 
-		// read should always return some data as select reported, it was ready to read when we got to this point.
+		// read should always return some data as select reported, it was ready to read when we got to this point,
+		// end of file means the line is hung up (e.g. modem carrier lost or the device is gone).
 		if n == 0 {
-			return read, &PortError{code: ReadFailed}
+			return read, &PortError{code: PortDisconnected}
 		}
 
-		copy(b[read:], buf[read:read+n])
+		if lineErrors {
+			var reported int
+			if events != nil {
+				reported = len(*events)
+			}
+			p.mu.Lock()
+			n = p.internal.parmrk.decode(b[read:], buf[read:read+n], read, p.cfg.Parity != NoParity, events)
+			p.mu.Unlock()
+			if n == 0 && (events == nil || len(*events) == reported) {
+				continue // nothing to return yet
+			}
+		} else {
+			copy(b[read:], buf[read:read+n])
+		}
 		read += n
 
-		now = time.Now()
-		if !now.Before(deadline) || p.internal.firstByteTimeout {
+		if firstByteTimeout || deadline.IsZero() || !time.Now().Before(deadline) {
 			return read, nil
 		}
 	}
@@ -183,63 +319,180 @@ func (p *Port) Read(b []byte) (int, error) {
 }
 
 func (p *Port) Write(b []byte) (int, error) {
-	if err := p.checkValid(); err != nil {
+	return p.WriteContext(context.Background(), b)
+}
+
+// WriteContext works like Write, but the pending operation is interrupted when ctx is done.
+// In that case the number of bytes written so far is returned along with a PortError wrapping ctx.Err(),
+// the port itself stays usable.
+func (p *Port) WriteContext(ctx context.Context, b []byte) (_ int, err error) {
+	defer p.wrapErr("write", &err)
+
+	if err := p.acquire(); err != nil {
 		return 0, err
 	}
+	defer p.release()
+	if err := ctx.Err(); err != nil {
+		return 0, &PortError{code: OperationCanceled, wrapped: err}
+	}
+
+	p.mu.Lock()
+	rs485, soft := p.cfg.RS485, p.internal.rs485Soft
+	p.mu.Unlock()
+
+	if soft {
+		return p.writeRS485(ctx, b, rs485)
+	}
+	return p.write(ctx, b)
+}
+
+func (p *Port) write(ctx context.Context, b []byte) (int, error) {
+	stop := p.internal.writeWake.wakeOnDone(ctx)
+	defer stop()
 
 	size, written := len(b), 0
 	fds := unixutils.NewFDSet(p.internal.handle)
-	clFds := unixutils.NewFDSet(p.internal.closePipeR)
+	clFds := unixutils.NewFDSet(p.internal.closePipeR, p.internal.writeWake.r)
 
-	deadline := time.Now().Add(time.Duration(p.internal.writeTimeout) * time.Millisecond)
+	p.mu.Lock()
+	writeTimeout := p.internal.writeTimeout
+	p.mu.Unlock()
+
+	var deadline time.Time // zero value means no timeout
+	if writeTimeout > 0 {
+		deadline = time.Now().Add(time.Duration(writeTimeout) * time.Millisecond)
+	}
+
+	for {
+		if p.internal.writeDeadline.expired() {
+			return written, newDeadlineExceededError()
+		}
 
-	for written < size {
 		n, err := unix.Write(p.internal.handle, b[written:])
-		if err != nil {
+		if n > 0 {
+			written += n
+		}
+		if err != nil && !errors.Is(err, unix.EAGAIN) && !errors.Is(err, unix.EINTR) {
 			return written, newPortOSError(err)
 		}
-
-		if p.internal.writeTimeout == 0 {
-			return n, nil
+		if written == size {
+			return written, nil
 		}
 
-		written += n
-		now := time.Now()
-		if p.internal.writeTimeout > 0 && !now.Before(deadline) {
-			return written, nil
+		timeout := timeoutUntil(deadline)
+		if timeout == 0 {
+			return written, nil // write timeout, unlike the deadline, is not an error
 		}
 
-		res, err := unixutils.Select(clFds, fds, fds, deadline.Sub(now))
+		res, err := unixutils.Select(clFds, fds, fds, minTimeout(timeout, timeoutUntil(p.internal.writeDeadline.get())))
 		if err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
 			return written, newPortOSError(err)
 		}
 
 		if res.IsReadable(p.internal.closePipeR) {
 			return written, &PortError{code: PortClosed}
 		}
-
-		if !res.IsWritable(p.internal.handle) {
-			return written, &PortError{code: WriteFailed}
+		if res.IsReadable(p.internal.writeWake.r) {
+			p.internal.writeWake.drain()
+			if err = ctx.Err(); err != nil {
+				return written, &PortError{code: OperationCanceled, wrapped: err}
+			}
 		}
 	}
-	return written, nil
 }
 
-func (p *Port) ResetInputBuffer() error {
-	if err := p.checkValid(); err != nil {
+// SetReadDeadline sets the deadline for future and pending Read calls, like net.Conn does.
+// A zero value for t means Read will not time out. After the deadline is exceeded Read returns
+// PortError with DeadlineExceeded code, which also satisfies errors.Is(err, os.ErrDeadlineExceeded).
+// The deadline works together with the read timeout, whichever expires first.
+func (p *Port) SetReadDeadline(t time.Time) (err error) {
+	defer p.wrapErr("set read deadline", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	p.internal.readDeadline.set(t)
+	p.internal.readWake.wake()
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for future and pending Write calls, like net.Conn does.
+// A zero value for t means Write will not time out. After the deadline is exceeded Write returns
+// PortError with DeadlineExceeded code, which also satisfies errors.Is(err, os.ErrDeadlineExceeded).
+func (p *Port) SetWriteDeadline(t time.Time) (err error) {
+	defer p.wrapErr("set write deadline", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	p.internal.writeDeadline.set(t)
+	p.internal.writeWake.wake()
+	return nil
+}
+
+// Drain waits until all data written to the port has been physically transmitted.
+// The write timeout and the write deadline are honoured.
+func (p *Port) Drain() error {
+	return p.DrainContext(context.Background())
+}
+
+// DrainContext works like Drain, but the waiting is interrupted when ctx is done.
+func (p *Port) DrainContext(ctx context.Context) (err error) {
+	defer p.wrapErr("drain", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+	if err := ctx.Err(); err != nil {
+		return &PortError{code: OperationCanceled, wrapped: err}
+	}
+
+	p.mu.Lock()
+	writeTimeout := p.internal.writeTimeout
+	p.mu.Unlock()
+
+	var deadline time.Time
+	if writeTimeout > 0 {
+		deadline = time.Now().Add(time.Duration(writeTimeout) * time.Millisecond)
+	}
+	if d := p.internal.writeDeadline.get(); !d.IsZero() && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	return p.drain(ctx, deadline) // already returned PortError
+}
+
+func (p *Port) ResetInputBuffer() (err error) {
+	defer p.wrapErr("reset input buffer", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
 	if err := unix.IoctlSetInt(p.internal.handle, ioctlTcflsh, unix.TCIFLUSH); err != nil {
 		return newPortOSError(err)
 	}
+	p.mu.Lock()
+	p.internal.parmrk.reset()
+	p.mu.Unlock()
 	return nil
 }
 
-func (p *Port) ResetOutputBuffer() error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) ResetOutputBuffer() (err error) {
+	defer p.wrapErr("reset output buffer", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
 	if err := unix.IoctlSetInt(p.internal.handle, ioctlTcflsh, unix.TCOFLUSH); err != nil {
 		return newPortOSError(err)
@@ -247,10 +500,16 @@ func (p *Port) ResetOutputBuffer() error {
 	return nil
 }
 
-func (p *Port) SetDTR(dtr bool) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetDTR(dtr bool) (err error) {
+	defer p.wrapErr("set dtr", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
+
+	p.internal.modemMu.Lock()
+	defer p.internal.modemMu.Unlock()
 
 	status, err := p.retrieveModemBitsStatus()
 	if err != nil {
@@ -264,10 +523,16 @@ func (p *Port) SetDTR(dtr bool) error {
 	return p.applyModemBitsStatus(status) // already returned PortError
 }
 
-func (p *Port) SetRTS(rts bool) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetRTS(rts bool) (err error) {
+	defer p.wrapErr("set rts", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
+
+	p.internal.modemMu.Lock()
+	defer p.internal.modemMu.Unlock()
 
 	status, err := p.retrieveModemBitsStatus()
 	if err != nil {
@@ -281,22 +546,62 @@ func (p *Port) SetRTS(rts bool) error {
 	return p.applyModemBitsStatus(status) // already returned PortError
 }
 
-func (p *Port) SetReadTimeout(t int) error {
-	if err := p.checkValid(); err != nil {
+// SetBreak turns the break condition on or off.
+// PortError with FunctionNotImplemented code is returned if the driver does not support it.
+func (p *Port) SetBreak(on bool) (err error) {
+	defer p.wrapErr("set break", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
+	return p.setBreak(on)
+}
+
+// setBreak turns the break condition on or off, the port must be acquired.
+func (p *Port) setBreak(on bool) error {
+	req := uint(unix.TIOCCBRK)
+	if on {
+		req = unix.TIOCSBRK
+	}
+	if err := unix.IoctlSetInt(p.internal.handle, req, 0); err != nil {
+		if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL) {
+			return &PortError{code: FunctionNotImplemented, wrapped: err}
+		}
+		return newPortOSError(err)
+	}
+	return nil
+}
+
+func (p *Port) SetReadTimeout(t int) (err error) {
+	defer p.wrapErr("set read timeout", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.setReadTimeoutValues(t)
+	p.cfg.ReadTimeout = t
 	return nil // timeout is done via select
 }
 
 // SetReadTimeoutEx Sets advanced timeouts.
 // Second argument was forget here due refactoring and keeping now for backward compatibility.
 // TODO Remove second argument in version v3.
-func (p *Port) SetReadTimeoutEx(t uint32, _ ...uint32) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetReadTimeoutEx(t uint32, _ ...uint32) (err error) {
+	defer p.wrapErr("set read timeout", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
 	s, err := p.retrieveTermSettings()
 	if err != nil {
@@ -322,36 +627,56 @@ func (p *Port) SetReadTimeoutEx(t uint32, _ ...uint32) error {
 
 	p.internal.firstByteTimeout = false
 	p.internal.readTimeout = int(t)
+	p.cfg.ReadTimeout = int(t)
 	return nil
 }
 
-func (p *Port) SetFirstByteReadTimeout(t uint32) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetFirstByteReadTimeout(t uint32) (err error) {
+	defer p.wrapErr("set read timeout", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
 	if t > 0 && t < 0xFFFFFFFF {
+		p.mu.Lock()
+		defer p.mu.Unlock()
 		p.internal.firstByteTimeout = true
 		p.internal.readTimeout = int(t)
+		p.cfg.ReadTimeout = int(t)
 		return nil
 	}
 	return &PortError{code: InvalidTimeoutValue}
 }
 
-func (p *Port) SetWriteTimeout(t int) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetWriteTimeout(t int) (err error) {
+	defer p.wrapErr("set write timeout", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.setWriteTimeoutValues(t)
+	p.cfg.WriteTimeout = t
 	return nil // timeout is done via select
 }
 
-func (p *Port) GetModemStatusBits() (*ModemStatusBits, error) {
-	if err := p.checkValid(); err != nil {
+func (p *Port) GetModemStatusBits() (_ *ModemStatusBits, err error) {
+	defer p.wrapErr("get modem status", &err)
+
+	if err := p.acquire(); err != nil {
 		return nil, err
 	}
+	defer p.release()
+
+	return p.modemStatusBits()
+}
 
+func (p *Port) modemStatusBits() (*ModemStatusBits, error) {
 	status, err := p.retrieveModemBitsStatus()
 	if err != nil {
 		return nil, err // port.retrieveModemBitsStatus() already returned PortError
@@ -364,6 +689,286 @@ func (p *Port) GetModemStatusBits() (*ModemStatusBits, error) {
 	}, nil
 }
 
+// Config returns the effective port configuration decoded from the current device settings.
+func (p *Port) Config() (_ *Config, err error) {
+	defer p.wrapErr("config", &err)
+
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	s, err := p.retrieveTermSettings()
+	if err != nil {
+		return nil, err // port.retrieveTermSettings() already returned PortError
+	}
+	rs485, err := p.RS485Config()
+	if err != nil {
+		return nil, err // port.RS485Config() already returned PortError
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &Config{
+		BaudRate:            s.baudrate(),
+		DataBits:            s.dataBits(),
+		Parity:              s.parity(),
+		StopBits:            s.stopBits(),
+		FlowControl:         s.flowControl(),
+		SoftwareFlowControl: s.softwareFlowControl(),
+		ReadTimeout:         p.internal.readTimeout,
+		WriteTimeout:        p.internal.writeTimeout,
+		HUPCL:               s.hupcl(),
+		RS485:               rs485,
+		Exclusive:           p.cfg.Exclusive,
+		UUCPLock:            p.internal.uucp != nil,
+		UUCPLockDir:         p.cfg.UUCPLockDir,
+		RestoreOnClose:      p.internal.saved != nil,
+		LineErrorReporting:  s.lineErrorReporting(),
+	}, nil
+}
+
+// FlowControl returns the hardware flow control currently applied to the port.
+func (p *Port) FlowControl() (_ FlowControl, err error) {
+	defer p.wrapErr("flow control", &err)
+
+	if err := p.acquire(); err != nil {
+		return NoFlowControl, err
+	}
+	defer p.release()
+
+	s, err := p.retrieveTermSettings()
+	if err != nil {
+		return NoFlowControl, err // port.retrieveTermSettings() already returned PortError
+	}
+	return s.flowControl(), nil
+}
+
+// RS485Config returns the RS-485 mode currently applied to the port.
+func (p *Port) RS485Config() (_ RS485Config, err error) {
+	defer p.wrapErr("rs485 config", &err)
+
+	if err := p.acquire(); err != nil {
+		return RS485Config{}, err
+	}
+	defer p.release()
+
+	p.mu.Lock()
+	soft, rs485 := p.internal.rs485Soft, p.cfg.RS485
+	p.mu.Unlock()
+
+	if soft {
+		return rs485, nil
+	}
+	c, err := getRS485(p.internal.handle)
+	if err != nil {
+		if errors.Is(err, unix.ENOTTY) {
+			return RS485Config{}, nil // RS-485 is not supported by the driver, so it is disabled
+		}
+		return RS485Config{}, newPortOSError(err)
+	}
+	return c, nil
+}
+
+// writeRS485 emulates RS-485 mode c by driving RTS line around the write. The received data is never discarded,
+// RxDuringTx is up to the transceiver in this mode.
+func (p *Port) writeRS485(ctx context.Context, b []byte, c RS485Config) (int, error) {
+	if err := p.SetRTS(c.RTSOnSend); err != nil {
+		return 0, err
+	}
+
+	n, err := 0, p.sleep(ctx, time.Duration(c.DelayRTSBeforeSend)*time.Millisecond)
+	if err == nil {
+		n, err = p.write(ctx, b)
+	}
+	if err == nil {
+		err = p.drain(ctx, time.Time{})
+	}
+	if err == nil {
+		err = p.sleep(ctx, time.Duration(c.DelayRTSAfterSend)*time.Millisecond)
+	}
+	if errors.Is(err, ErrClosed) {
+		return n, err
+	}
+	return n, multierr.Append(err, p.SetRTS(c.RTSAfterSend))
+}
+
+func (p *Port) applyRS485() error {
+	if !p.cfg.RS485.Enabled && !p.internal.rs485Kernel {
+		p.internal.rs485Soft = false
+		return nil
+	}
+
+	err := setRS485(p.internal.handle, p.cfg.RS485)
+	switch {
+	case err == nil:
+		p.internal.rs485Kernel = p.cfg.RS485.Enabled
+		p.internal.rs485Soft = false
+		return nil
+	case errors.Is(err, unix.ENOTTY):
+		// Not supported by the driver, fallback to software emulation
+		p.internal.rs485Kernel = false
+		p.internal.rs485Soft = false
+		if !p.cfg.RS485.Enabled {
+			return nil
+		}
+		if p.cfg.FlowControl == RTSCTSFlowControl {
+			// The emulation drives RTS, so it can not be used for the flow control
+			return newConfigError(InvalidFlowControl, "FlowControl", "rtscts with rs485")
+		}
+		if err = p.SetRTS(p.cfg.RS485.RTSAfterSend); err != nil {
+			return err // port.SetRTS() already returned PortError
+		}
+		p.internal.rs485Soft = true
+		return nil
+	default:
+		return newPortOSError(err)
+	}
+}
+
+// drainPollInterval is the interval of the output queue size polling by Drain().
+const drainPollInterval = 10 * time.Millisecond
+
+// drain waits until the output queue is empty and transmitted. The queue size is polled, so the waiting may be
+// abandoned when ctx is done, the deadline is exceeded or the port is closed. The final tcdrain waits for the
+// transmitter only, the drivers limit that waiting with a timeout. Zero deadline means no deadline.
+func (p *Port) drain(ctx context.Context, deadline time.Time) error {
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := ioctlOutQueue(p.internal.handle)
+		if err != nil {
+			return newPortOSError(err)
+		}
+		if n == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return &PortError{code: OperationCanceled, wrapped: ctx.Err()}
+		case <-expired:
+			return newDeadlineExceededError()
+		case <-p.internal.closed:
+			return &PortError{code: PortClosed}
+		case <-ticker.C:
+		}
+	}
+	if err := tcdrain(p.internal.handle); err != nil {
+		return newPortOSError(err)
+	}
+	return nil
+}
+
+// WaitModemStatusChange blocks until any of the modem status lines selected by mask changes
+// and returns the new status. The line transition counters (linux TIOCGICOUNT) are polled, so short pulses
+// are not missed, the status lines are polled if the counters are not supported by the driver.
+// TIOCMIWAIT is not used, as the blocked ioctl can not be interrupted by ctx or Close.
+func (p *Port) WaitModemStatusChange(ctx context.Context, mask ModemStatusMask) (_ *ModemStatusBits, err error) {
+	defer p.wrapErr("wait modem status", &err)
+
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	w, err := p.newModemWatcher(mask)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+	if _, err = w.wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.modemStatusBits()
+}
+
+// modemWatcher detects the modem status lines transitions since the previous wait call.
+type modemWatcher struct {
+	p        *Port
+	mask     ModemStatusMask
+	counters *Counters        // transition counters baseline, nil if not supported by the driver
+	status   *ModemStatusBits // status lines baseline, used if the counters are not supported
+}
+
+func (p *Port) newModemWatcher(mask ModemStatusMask) (*modemWatcher, error) {
+	w := &modemWatcher{p: p, mask: mask.orAll()}
+	c, err := ioctlCounters(p.internal.handle)
+	switch {
+	case err == nil:
+		w.counters = &c
+	case errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL):
+		if w.status, err = p.modemStatusBits(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newPortOSError(err)
+	}
+	return w, nil
+}
+
+// wait blocks until any of the selected lines transitions and returns the lines transitioned since
+// the previous call. A line may be back to its previous level, e.g. after a short pulse.
+func (w *modemWatcher) wait(ctx context.Context) (ModemStatusMask, error) {
+	if err := w.p.acquire(); err != nil {
+		return 0, err
+	}
+	defer w.p.release()
+
+	var changed ModemStatusMask
+	err := w.p.pollModem(ctx, func() (bool, error) {
+		if w.counters != nil {
+			c, err := ioctlCounters(w.p.internal.handle)
+			if err != nil {
+				return false, newPortOSError(err)
+			}
+			changed = c.Delta(*w.counters).modemLines() & w.mask
+			w.counters = &c
+			return changed != 0, nil
+		}
+		status, err := w.p.modemStatusBits()
+		if err != nil {
+			return false, err
+		}
+		changed = w.status.diff(status) & w.mask
+		w.status = status
+		return changed != 0, nil
+	})
+	if err != nil {
+		return 0, err // port.pollModem() already returned PortError
+	}
+	return changed, nil
+}
+
+func (w *modemWatcher) close() {}
+
+// Counters returns the serial line interrupt and error counters (linux only).
+// PortError with FunctionNotImplemented code is returned if not supported by the driver or the platform.
+func (p *Port) Counters() (_ *Counters, err error) {
+	defer p.wrapErr("counters", &err)
+
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	c, err := ioctlCounters(p.internal.handle)
+	if err != nil {
+		if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL) {
+			return nil, &PortError{code: FunctionNotImplemented, wrapped: err}
+		}
+		return nil, newPortOSError(err)
+	}
+	return &c, nil
+}
+
 func (p *Port) setReadTimeoutValues(t int) {
 	p.internal.firstByteTimeout = false
 	p.internal.readTimeout = t
@@ -373,8 +978,17 @@ func (p *Port) setWriteTimeoutValues(t int) {
 	p.internal.writeTimeout = t
 }
 
+// The modem and output queue ioctls are replaced in tests, as the pseudo terminals have no modem lines
+// and the output is never queued.
+var (
+	ioctlModemBits    = func(fd int) (int, error) { return unix.IoctlGetInt(fd, unix.TIOCMGET) }
+	ioctlSetModemBits = func(fd, status int) error { return unix.IoctlSetPointerInt(fd, unix.TIOCMSET, status) }
+	ioctlCounters     = tiocgicount
+	ioctlOutQueue     = func(fd int) (int, error) { return unix.IoctlGetInt(fd, unix.TIOCOUTQ) }
+)
+
 func (p *Port) retrieveModemBitsStatus() (int, error) {
-	s, err := unix.IoctlGetInt(p.internal.handle, unix.TIOCMGET)
+	s, err := ioctlModemBits(p.internal.handle)
 	if err != nil {
 		return 0, newPortOSError(err)
 	}
@@ -382,80 +996,197 @@ func (p *Port) retrieveModemBitsStatus() (int, error) {
 }
 
 func (p *Port) applyModemBitsStatus(status int) error {
-	if err := unix.IoctlSetPointerInt(p.internal.handle, unix.TIOCMSET, status); err != nil {
+	if err := ioctlSetModemBits(p.internal.handle, status); err != nil {
 		return newPortOSError(err)
 	}
 	return nil
 }
 
-func (p *Port) reconfigure() error {
-	if err := p.checkValid(); err != nil {
-		return err
+// lockExclusive acquires the exclusive access to the device according to the mode.
+func (p *Port) lockExclusive(mode ExclusiveMode) *PortError {
+	// The flock is taken first: TIOCEXCL set by the process failed to acquire the flock would be cleared on close,
+	// dropping the exclusive access of the current owner (e.g. the second open is done by root).
+	if mode == ExclusiveFlock || mode == ExclusiveBoth {
+		if err := unix.Flock(p.internal.handle, unix.LOCK_EX|unix.LOCK_NB); err != nil {
+			if errors.Is(err, unix.EWOULDBLOCK) {
+				return &PortError{code: PortBusy, wrapped: &LockHolder{PID: flockHolder(p.internal.handle), Lock: "flock"}}
+			}
+			return newPortOSError(err)
+		}
+	}
+	if mode == ExclusiveTIOCEXCL || mode == ExclusiveBoth {
+		// does nothing in build for android
+		excl, err := accquireExclusiveAccess(p.internal.handle)
+		if err != nil {
+			if errors.Is(err, unix.ENOTTY) {
+				return &PortError{code: InvalidSerialPort, wrapped: err}
+			}
+			return newPortOSError(err)
+		}
+		p.internal.tiocexcl = excl
+	}
+	return nil
+}
+
+func (p *Port) saveState() (*savedState, error) {
+	s, err := p.retrieveTermSettings()
+	if err != nil {
+		return nil, err // port.retrieveTermSettings() already returned PortError
+	}
+
+	state := &savedState{settings: s, modem: true}
+	bits, err := unix.IoctlGetInt(p.internal.handle, unix.TIOCMGET)
+	switch {
+	case err == nil:
+		state.modemBits = bits & (unix.TIOCM_DTR | unix.TIOCM_RTS)
+	case errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL):
+		state.modem = false // e.g. pseudo terminal
+	default:
+		return nil, newPortOSError(err)
+	}
+	return state, nil
+}
+
+// restoreState restores the device state saved on open (if any).
+func (p *Port) restoreState() error {
+	state := p.internal.saved
+	if state == nil {
+		return nil
+	}
+	p.internal.saved = nil
+
+	if err := p.applyTermSettings(state.settings); err != nil {
+		return err // port.applyTermSettings() already returned PortError
+	}
+	if !state.modem {
+		return nil
+	}
+	status, err := p.retrieveModemBitsStatus()
+	if err != nil {
+		return err // port.retrieveModemBitsStatus() already returned PortError
 	}
+	status &^= unix.TIOCM_DTR | unix.TIOCM_RTS
+	return p.applyModemBitsStatus(status | state.modemBits) // already returned PortError
+}
 
+func (p *Port) reconfigure() error {
 	s, err := p.retrieveTermSettings()
 	if err != nil {
 		return err // port.retrieveTermSettings() already returned PortError
 	}
 
-	if err := s.setBaudrate(p.baudRate); err != nil {
+	if err := s.setBaudrate(p.cfg.BaudRate); err != nil {
 		return err
 	}
-	if err := s.setParity(p.parity); err != nil {
+	if err := s.setParity(p.cfg.Parity); err != nil {
 		return err
 	}
-	if err := s.setDataBits(p.dataBits); err != nil {
+	if err := s.setDataBits(p.cfg.DataBits); err != nil {
 		return err
 	}
-	if err := s.setStopBits(p.stopBits); err != nil {
+	if err := s.setStopBits(p.cfg.StopBits); err != nil {
 		return err
 	}
-	s.setRawMode(p.hupcl)
-	// Explicitly disable RTS/CTS flow control
-	s.setCtsRts(false)
+	if err := s.setFlowControl(p.cfg.FlowControl); err != nil {
+		return err
+	}
+	s.setRawMode(p.cfg.HUPCL)
+	s.setSoftwareFlowControl(p.cfg.SoftwareFlowControl) // must follow setRawMode()
+	s.setLineErrorReporting(p.cfg.LineErrorReporting)   // must follow setRawMode()
 
-	return p.applyTermSettings(s) // already returned PortError
+	if err := p.applyTermSettings(s); err != nil {
+		return err // port.applyTermSettings() already returned PortError
+	}
+	return p.applyRS485() // already returned PortError
 }
 
-func GetPortsList() ([]string, error) {
-	files, err := os.ReadDir(devicesBasePath)
-	if err != nil {
-		return nil, err
+func isHandleValid(h int) bool {
+	return h != 0
+}
+
+func (p *port) closePipes() error {
+	return multierr.Combine(
+		unix.Close(p.closePipeW),
+		unix.Close(p.closePipeR),
+	)
+}
+
+// timeoutUntil returns the duration left until the deadline suitable for unixutils.Select:
+// negative duration (wait forever) for zero deadline and never negative otherwise.
+func timeoutUntil(deadline time.Time) time.Duration {
+	if deadline.IsZero() {
+		return -1
 	}
+	if d := time.Until(deadline); d > 0 {
+		return d
+	}
+	return 0
+}
 
-	ports := make([]string, 0, len(files))
-	for _, f := range files {
-		// Skip folders
-		if f.IsDir() {
-			continue
-		}
+// minTimeout returns the shortest of two unixutils.Select timeouts, negative one means no timeout.
+func minTimeout(a, b time.Duration) time.Duration {
+	if a < 0 || (b >= 0 && b < a) {
+		return b
+	}
+	return a
+}
 
-		// Keep only devices with the correct name
-		if !portNameRx.MatchString(f.Name()) {
-			continue
-		}
+// wakePipe is a non-blocking self-pipe used to interrupt a pending select.
+type wakePipe struct {
+	r int
+	w int
+}
 
-		name := path.Join(devicesBasePath, f.Name())
+func newWakePipe() (wakePipe, error) {
+	fds := []int{0, 0}
+	if err := unix.Pipe(fds); err != nil {
+		return wakePipe{}, err
+	}
+	w := wakePipe{r: fds[0], w: fds[1]}
+	if err := multierr.Append(unix.SetNonblock(w.r, true), unix.SetNonblock(w.w, true)); err != nil {
+		return wakePipe{}, multierr.Append(err, w.close())
+	}
+	return w, nil
+}
 
-		// Check if serial port is real or is a placeholder serial port "ttySxx"
-		if strings.HasPrefix(f.Name(), "ttyS") {
-			if port, err := Open(name); err != nil {
-				var portErr *PortError
-				if errors.As(err, &portErr) && portErr.Code() == InvalidSerialPort {
-					continue
-				}
-			} else {
-				_ = port.Close()
-			}
-		}
+// wake interrupts a pending select. Full pipe means it is already signaled, so the error is ignored.
+func (w wakePipe) wake() {
+	_, _ = unix.Write(w.w, zeroByte)
+}
 
-		// Save serial port in the resulting list
-		ports = append(ports, name)
+// drain consumes all pending wake signals.
+func (w wakePipe) drain() {
+	var buf [16]byte
+	for {
+		if n, err := unix.Read(w.r, buf[:]); n <= 0 || err != nil {
+			return
+		}
 	}
+}
 
-	return ports, nil
+// wakeOnDone wakes the pipe when ctx is done. Returned function must be called to release resources.
+func (w wakePipe) wakeOnDone(ctx context.Context) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	stopCh, doneCh := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		select {
+		case <-ctx.Done():
+			w.wake()
+		case <-stopCh:
+		}
+	}()
+	return func() {
+		close(stopCh)
+		<-doneCh
+	}
 }
 
-func isHandleValid(h int) bool {
-	return h != 0
+func (w wakePipe) close() error {
+	return multierr.Combine(
+		unix.Close(w.w),
+		unix.Close(w.r),
+	)
 }
@@ -16,7 +16,33 @@ package serial
 // https://playground.arduino.cc/Interfacing/CPPWindows
 // https://www.tldp.org/HOWTO/Serial-HOWTO-19.html
 
-import "syscall"
+import (
+	"context"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/multierr"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	defaultReadTimeout  = -1 // Block until the buffer is full
+	defaultWriteTimeout = -1 // Block until all the data written
+)
+
+// platformCapabilities describes the configuration supported by the platform, see Config.Validate().
+var platformCapabilities = capabilities{
+	baudRate:               nil, // any baud rate is passed to the driver
+	markSpaceParity:        true,
+	onePointFiveStopBits:   true,
+	dtrDSRFlowControl:      true,
+	softwareFlowControlAny: false,
+	rs485WithRTSCTS:        false, // both use RTS_CONTROL
+	lineErrorReporting:     false,
+	exclusiveModes:         false, // the port is always opened exclusively
+	uucpLock:               false,
+}
 
 var parityMap = map[Parity]byte{
 	NoParity:    0,
@@ -35,12 +61,38 @@ var stopBitsMap = map[StopBits]byte{
 type port struct {
 	handle   syscall.Handle
 	timeouts *commTimeouts
+
+	readDeadline         deadline
+	readDeadlineChanged  chan struct{}
+	writeDeadline        deadline
+	writeDeadlineChanged chan struct{}
+
+	closed chan struct{} // closed on port close
+
+	countersMu sync.Mutex
+	counters   Counters // accumulated from ClearCommError results and read/written bytes
+
+	saved *savedState // device state to restore on close (if requested)
 }
 
-func Open(name string, opts ...Option) (*Port, error) {
+// savedState is the device state saved on open to be restored on close, see WithRestoreOnClose.
+// DTR and RTS lines control is a part of the DCB.
+type savedState struct {
+	params   dcb
+	timeouts commTimeouts
+}
+
+// OpenWithConfig opens the serial port with the configuration c, which is validated before the port opened.
+func OpenWithConfig(name string, c Config) (_ *Port, err error) {
+	defer func() { err = withOp(err, "open", name) }()
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
 	path, err := syscall.UTF16PtrFromString("\\\\.\\" + name)
 	if err != nil {
-		return nil, err
+		return nil, &PortError{code: InvalidSerialPort, wrapped: err}
 	}
 
 	handle, err := syscall.CreateFile(
@@ -52,13 +104,7 @@ func Open(name string, opts ...Option) (*Port, error) {
 		syscall.FILE_ATTRIBUTE_NORMAL|syscall.FILE_FLAG_OVERLAPPED,
 		0)
 	if err != nil {
-		switch err {
-		case syscall.ERROR_ACCESS_DENIED:
-			return nil, &PortError{code: PortBusy}
-		case syscall.ERROR_FILE_NOT_FOUND:
-			return nil, &PortError{code: PortNotFound}
-		}
-		return nil, err
+		return nil, openError(err)
 	}
 
 	port := newWithDefaults(name, &port{
@@ -72,8 +118,18 @@ func Open(name string, opts ...Option) (*Port, error) {
 			WriteTotalTimeoutMultiplier: 0,
 			WriteTotalTimeoutConstant:   0,
 		},
+		readDeadlineChanged:  make(chan struct{}, 1),
+		writeDeadlineChanged: make(chan struct{}, 1),
+		closed:               make(chan struct{}),
 	})
-	if err = port.Reconfigure(opts...); err != nil {
+	port.cfg = port.cfg.withOpenOnly(c)
+	if c.RestoreOnClose {
+		if port.internal.saved, err = port.saveState(); err != nil {
+			port.Close()
+			return nil, err
+		}
+	}
+	if err = port.ApplyConfig(c); err != nil {
 		port.Close()
 		return nil, err
 	}
@@ -81,65 +137,91 @@ func Open(name string, opts ...Option) (*Port, error) {
 	return port, nil
 }
 
-func (p *Port) Close() error {
-	if err := p.checkValid(); err != nil {
-		return err
+// openError maps the error returned by CreateFile to PortError.
+func openError(err error) error {
+	switch err {
+	case syscall.ERROR_ACCESS_DENIED, windows.ERROR_SHARING_VIOLATION: // the port is always opened exclusively
+		return &PortError{code: PortBusy, wrapped: err}
+	case syscall.ERROR_FILE_NOT_FOUND, syscall.ERROR_PATH_NOT_FOUND, windows.ERROR_INVALID_NAME:
+		return &PortError{code: PortNotFound, wrapped: err}
+	default:
+		return newPortOSError(err)
 	}
+}
 
-	if p.internal.handle == syscall.InvalidHandle {
-		return nil
+// Close closes the port. The pending operations are interrupted with PortClosed error,
+// the handle is closed after all of them returned.
+func (p *Port) Close() (err error) {
+	defer p.wrapErr("close", &err)
+
+	idle, err := p.markClosed()
+	if err != nil {
+		return err
 	}
-	err := syscall.CloseHandle(p.internal.handle)
+	close(p.internal.closed) // cancels the pending overlapped operations, see cancelOnDone()
+	<-idle
+
+	err = multierr.Append(p.restoreState(), syscall.CloseHandle(p.internal.handle))
 	p.internal.handle = syscall.InvalidHandle
 	if err != nil {
-		return &PortError{code: OsError, wrapped: err}
+		return newPortOSError(err)
 	}
 	return nil
 }
 
-func (p *Port) Reconfigure(opts ...Option) error {
-	if err := p.checkValid(); err != nil {
-		return err
-	}
+func (p *Port) ReadyToRead() (_ uint32, err error) {
+	defer p.wrapErr("ready to read", &err)
 
-	for _, o := range opts {
-		o(p)
-	}
-	return p.reconfigure()
-}
-
-func (p *Port) ReadyToRead() (uint32, error) {
-	if err := p.checkValid(); err != nil {
+	if err := p.acquire(); err != nil {
 		return 0, err
 	}
+	defer p.release()
 
-	var errs uint32
 	var stat comstat
-	if err := clearCommError(p.internal.handle, &errs, &stat); err != nil {
-		return 0, &PortError{code: OsError, wrapped: err}
+	if err := p.retrieveCommStatus(&stat); err != nil {
+		return 0, newPortOSError(err)
 	}
 	return stat.inque, nil
 }
 
 func (p *Port) Read(b []byte) (int, error) {
-	if err := p.checkValid(); err != nil {
+	return p.ReadContext(context.Background(), b)
+}
+
+// ReadContext works like Read, but the pending operation is interrupted when ctx is done.
+// In that case the bytes read so far are returned along with a PortError wrapping ctx.Err(),
+// the port itself stays usable.
+func (p *Port) ReadContext(ctx context.Context, b []byte) (_ int, err error) {
+	defer p.wrapErr("read", &err)
+
+	if err := p.acquire(); err != nil {
 		return 0, err
 	}
+	defer p.release()
+	if err := ctx.Err(); err != nil {
+		return 0, &PortError{code: OperationCanceled, wrapped: err}
+	}
+	if p.internal.readDeadline.expired() {
+		return 0, newDeadlineExceededError()
+	}
 
 	if p.internal.handle == syscall.InvalidHandle {
 		return 0, &PortError{code: PortClosed, wrapped: nil}
 	}
 	handle := p.internal.handle
 
-	errs := new(uint32)
 	stat := new(comstat)
-	if err := clearCommError(handle, errs, stat); err != nil {
-		return 0, &PortError{code: InvalidSerialPort, wrapped: err}
+	if err := p.retrieveCommStatus(stat); err != nil {
+		return 0, wrapOSError(InvalidSerialPort, err)
 	}
 
+	p.mu.Lock()
+	timeouts := *p.internal.timeouts
+	p.mu.Unlock()
+
 	size := uint32(len(b))
 	var readSize uint32
-	if p.internal.timeouts.ReadTotalTimeoutConstant == 0 && p.internal.timeouts.ReadTotalTimeoutMultiplier == 0 {
+	if timeouts.ReadTotalTimeoutConstant == 0 && timeouts.ReadTotalTimeoutMultiplier == 0 {
 		if stat.inque < size {
 			readSize = stat.inque
 		} else {
@@ -153,16 +235,28 @@ func (p *Port) Read(b []byte) (int, error) {
 		var read uint32
 		overlapped, err := createOverlappedStruct()
 		if err != nil {
-			return 0, &PortError{code: OsError, wrapped: err}
+			return 0, newPortOSError(err)
 		}
 		defer syscall.CloseHandle(overlapped.HEvent)
 		err = syscall.ReadFile(handle, b[:readSize], &read, overlapped)
 		if err != nil && err != syscall.ERROR_IO_PENDING {
-			return 0, &PortError{code: OsError, wrapped: err}
+			return 0, newPortOSError(err)
 		}
+		stop := p.internal.cancelOnDone(ctx, overlapped, &p.internal.readDeadline, p.internal.readDeadlineChanged)
 		err = getOverlappedResult(handle, overlapped, &read, true)
+		stop()
+		p.countBytes(read, 0)
 		if err != nil && err != syscall.ERROR_OPERATION_ABORTED {
-			return 0, &PortError{code: OsError, wrapped: err}
+			return 0, newPortOSError(err)
+		}
+		if p.internal.isClosed() {
+			return int(read), &PortError{code: PortClosed}
+		}
+		if err = ctx.Err(); err != nil {
+			return int(read), &PortError{code: OperationCanceled, wrapped: err}
+		}
+		if p.internal.readDeadline.expired() {
+			return int(read), newDeadlineExceededError()
 		}
 		return int(read), nil
 	} else {
@@ -170,54 +264,167 @@ func (p *Port) Read(b []byte) (int, error) {
 	}
 }
 
+// ReadWithErrors works like Read, line errors reporting is not supported on windows, so no events are returned.
+func (p *Port) ReadWithErrors(b []byte) (int, []LineEvent, error) {
+	n, err := p.Read(b)
+	return n, nil, err
+}
+
 func (p *Port) Write(b []byte) (int, error) {
-	if err := p.checkValid(); err != nil {
+	return p.WriteContext(context.Background(), b)
+}
+
+// WriteContext works like Write, but the pending operation is interrupted when ctx is done.
+// In that case the number of bytes written so far is returned along with a PortError wrapping ctx.Err(),
+// the port itself stays usable.
+func (p *Port) WriteContext(ctx context.Context, b []byte) (_ int, err error) {
+	defer p.wrapErr("write", &err)
+
+	if err := p.acquire(); err != nil {
 		return 0, err
 	}
+	defer p.release()
+	if err := ctx.Err(); err != nil {
+		return 0, &PortError{code: OperationCanceled, wrapped: err}
+	}
+	if p.internal.writeDeadline.expired() {
+		return 0, newDeadlineExceededError()
+	}
 
 	h := p.internal.handle
-	errs := new(uint32)
 	stat := new(comstat)
-	if err := clearCommError(h, errs, stat); err != nil {
-		return 0, &PortError{code: InvalidSerialPort, wrapped: err}
+	if err := p.retrieveCommStatus(stat); err != nil {
+		return 0, wrapOSError(InvalidSerialPort, err)
 	}
 
 	overlapped, err := createOverlappedStruct()
 	if err != nil {
-		return 0, err
+		return 0, newPortOSError(err)
 	}
 	defer syscall.CloseHandle(overlapped.HEvent)
 	var written uint32
 	err = syscall.WriteFile(h, b, &written, overlapped)
 	if err == nil || err == syscall.ERROR_IO_PENDING || err == syscall.ERROR_OPERATION_ABORTED {
+		stop := p.internal.cancelOnDone(ctx, overlapped, &p.internal.writeDeadline, p.internal.writeDeadlineChanged)
 		err = getOverlappedResult(h, overlapped, &written, true)
+		stop()
+		p.countBytes(0, written)
+		if p.internal.isClosed() {
+			return int(written), &PortError{code: PortClosed}
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return int(written), &PortError{code: OperationCanceled, wrapped: ctxErr}
+		}
+		if p.internal.writeDeadline.expired() {
+			return int(written), newDeadlineExceededError()
+		}
 		if err == nil || err == syscall.ERROR_OPERATION_ABORTED {
 			return int(written), nil
 		}
 	}
-	return int(written), err
+	return int(written), newPortOSError(err)
 }
 
-func (p *Port) ResetInputBuffer() error {
-	if err := p.checkValid(); err != nil {
+// SetReadDeadline sets the deadline for future and pending Read calls, like net.Conn does.
+// A zero value for t means Read will not time out. After the deadline is exceeded Read returns
+// PortError with DeadlineExceeded code, which also satisfies errors.Is(err, os.ErrDeadlineExceeded).
+// The deadline works together with the read timeout, whichever expires first.
+func (p *Port) SetReadDeadline(t time.Time) (err error) {
+	defer p.wrapErr("set read deadline", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
-	return purgeComm(p.internal.handle, purgeRxClear|purgeRxAbort)
+	p.internal.readDeadline.set(t)
+	notify(p.internal.readDeadlineChanged)
+	return nil
 }
 
-func (p *Port) ResetOutputBuffer() error {
-	if err := p.checkValid(); err != nil {
+// SetWriteDeadline sets the deadline for future and pending Write calls, like net.Conn does.
+// A zero value for t means Write will not time out. After the deadline is exceeded Write returns
+// PortError with DeadlineExceeded code, which also satisfies errors.Is(err, os.ErrDeadlineExceeded).
+func (p *Port) SetWriteDeadline(t time.Time) (err error) {
+	defer p.wrapErr("set write deadline", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
+
+	p.internal.writeDeadline.set(t)
+	notify(p.internal.writeDeadlineChanged)
+	return nil
+}
 
-	return purgeComm(p.internal.handle, purgeTxClear|purgeTxAbort)
+// Drain waits until all data written to the port has been physically transmitted.
+// The write timeout and the write deadline are honoured.
+func (p *Port) Drain() error {
+	return p.DrainContext(context.Background())
 }
 
-func (p *Port) SetDTR(dtr bool) error {
-	if err := p.checkValid(); err != nil {
+// DrainContext works like Drain, but the waiting is interrupted when ctx is done.
+func (p *Port) DrainContext(ctx context.Context) (err error) {
+	defer p.wrapErr("drain", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
+	if err := ctx.Err(); err != nil {
+		return &PortError{code: OperationCanceled, wrapped: err}
+	}
+
+	p.mu.Lock()
+	writeTimeout := p.internal.timeouts.WriteTotalTimeoutConstant
+	p.mu.Unlock()
+
+	var deadline time.Time
+	if c := writeTimeout; c > 0 && c < 0xFFFFFFFF {
+		deadline = time.Now().Add(time.Duration(c) * time.Millisecond)
+	}
+	if d := p.internal.writeDeadline.get(); !d.IsZero() && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	return p.blockingCall(ctx, deadline, flushFileBuffers) // already returned PortError
+}
+
+func (p *Port) ResetInputBuffer() (err error) {
+	defer p.wrapErr("reset input buffer", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	if err := purgeComm(p.internal.handle, purgeRxClear|purgeRxAbort); err != nil {
+		return newPortOSError(err)
+	}
+	return nil
+}
+
+func (p *Port) ResetOutputBuffer() (err error) {
+	defer p.wrapErr("reset output buffer", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	if err := purgeComm(p.internal.handle, purgeTxClear|purgeTxAbort); err != nil {
+		return newPortOSError(err)
+	}
+	return nil
+}
+
+func (p *Port) SetDTR(dtr bool) (err error) {
+	defer p.wrapErr("set dtr", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
 
 	// Like for RTS there are problems with the escapeCommFunction
 	// observed behaviour was that DTR is set from false -> true
@@ -242,11 +449,13 @@ func (p *Port) SetDTR(dtr bool) error {
 
 	// The following seems a more reliable way to do it
 
-	p.hupcl = dtr
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cfg.HUPCL = dtr
 
 	params := &dcb{}
 	if err := getCommState(p.internal.handle, params); err != nil {
-		return &PortError{wrapped: err}
+		return newPortOSError(err)
 	}
 
 	params.Flags &= dcbDTRControlDisableMask
@@ -255,16 +464,19 @@ func (p *Port) SetDTR(dtr bool) error {
 	}
 
 	if err := setCommState(p.internal.handle, params); err != nil {
-		return &PortError{wrapped: err}
+		return newPortOSError(err)
 	}
 
 	return nil
 }
 
-func (p *Port) SetRTS(rts bool) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetRTS(rts bool) (err error) {
+	defer p.wrapErr("set rts", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
 	// It seems that there is a bug in the Windows VCP driver:
 	// it doesn't send USB control message when the RTS bit is
@@ -288,68 +500,125 @@ func (p *Port) SetRTS(rts bool) error {
 
 	// The following seems a more reliable way to do it
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	params := &dcb{}
 	if err := getCommState(p.internal.handle, params); err != nil {
-		return &PortError{wrapped: err}
+		return newPortOSError(err)
 	}
 	params.Flags &= dcbRTSControlDisableMask
 	if rts {
 		params.Flags |= dcbRTSControlEnable
 	}
 	if err := setCommState(p.internal.handle, params); err != nil {
-		return &PortError{wrapped: err}
+		return newPortOSError(err)
+	}
+	return nil
+}
+
+// SetBreak turns the break condition on or off.
+// PortError with FunctionNotImplemented code is returned if the driver does not support it.
+func (p *Port) SetBreak(on bool) (err error) {
+	defer p.wrapErr("set break", &err)
+
+	if err := p.acquire(); err != nil {
+		return err
+	}
+	defer p.release()
+
+	return p.setBreak(on)
+}
+
+// setBreak turns the break condition on or off, the port must be acquired.
+func (p *Port) setBreak(on bool) error {
+	f := clearCommBreak
+	if on {
+		f = setCommBreak
+	}
+	if err := f(p.internal.handle); err != nil {
+		if err == windows.ERROR_NOT_SUPPORTED || err == windows.ERROR_INVALID_FUNCTION {
+			return &PortError{code: FunctionNotImplemented, wrapped: err}
+		}
+		return newPortOSError(err)
 	}
 	return nil
 }
 
-func (p *Port) SetReadTimeout(t int) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetReadTimeout(t int) (err error) {
+	defer p.wrapErr("set read timeout", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.setReadTimeoutValues(t)
+	p.cfg.ReadTimeout = t
 	return p.reconfigure()
 }
 
-func (p *Port) SetReadTimeoutEx(t, i uint32) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetReadTimeoutEx(t, i uint32) (err error) {
+	defer p.wrapErr("set read timeout", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.internal.timeouts.ReadIntervalTimeout = i
 	p.internal.timeouts.ReadTotalTimeoutMultiplier = 0
 	p.internal.timeouts.ReadTotalTimeoutConstant = t
+	p.cfg.ReadTimeout = int(t)
 	return p.reconfigure()
 }
 
-func (p *Port) SetFirstByteReadTimeout(t uint32) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetFirstByteReadTimeout(t uint32) (err error) {
+	defer p.wrapErr("set read timeout", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
 	if t > 0 && t < 0xFFFFFFFF {
+		p.mu.Lock()
+		defer p.mu.Unlock()
 		p.internal.timeouts.ReadIntervalTimeout = 0xFFFFFFFF
 		p.internal.timeouts.ReadTotalTimeoutMultiplier = 0xFFFFFFFF
 		p.internal.timeouts.ReadTotalTimeoutConstant = t
+		p.cfg.ReadTimeout = int(t)
 		return p.reconfigure()
 	} else {
 		return &PortError{code: InvalidTimeoutValue}
 	}
 }
 
-func (p *Port) SetWriteTimeout(t int) error {
-	if err := p.checkValid(); err != nil {
+func (p *Port) SetWriteTimeout(t int) (err error) {
+	defer p.wrapErr("set write timeout", &err)
+
+	if err := p.acquire(); err != nil {
 		return err
 	}
+	defer p.release()
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.setWriteTimeoutValues(t)
+	p.cfg.WriteTimeout = t
 	return p.reconfigure()
 }
 
-func (p *Port) GetModemStatusBits() (*ModemStatusBits, error) {
-	if err := p.checkValid(); err != nil {
+func (p *Port) GetModemStatusBits() (_ *ModemStatusBits, err error) {
+	defer p.wrapErr("get modem status", &err)
+
+	if err := p.acquire(); err != nil {
 		return nil, err
 	}
+	defer p.release()
 
 	var bits uint32
 	if !getCommModemStatus(p.internal.handle, &bits) {
@@ -363,6 +632,316 @@ func (p *Port) GetModemStatusBits() (*ModemStatusBits, error) {
 	}, nil
 }
 
+// FlowControl returns the hardware flow control currently applied to the port.
+func (p *Port) FlowControl() (_ FlowControl, err error) {
+	defer p.wrapErr("flow control", &err)
+
+	if err := p.acquire(); err != nil {
+		return NoFlowControl, err
+	}
+	defer p.release()
+
+	params := &dcb{}
+	if err := getCommState(p.internal.handle, params); err != nil {
+		return NoFlowControl, newPortOSError(err)
+	}
+	return params.flowControl(), nil
+}
+
+// Config returns the effective port configuration decoded from the current device settings.
+func (p *Port) Config() (_ *Config, err error) {
+	defer p.wrapErr("config", &err)
+
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	params := &dcb{}
+	if err := getCommState(p.internal.handle, params); err != nil {
+		return nil, newPortOSError(err)
+	}
+	c := &Config{
+		BaudRate:    int(params.BaudRate),
+		DataBits:    int(params.ByteSize),
+		FlowControl: params.flowControl(),
+		SoftwareFlowControl: SoftwareFlowControl{
+			Input:  params.Flags&dcbInX != 0,
+			Output: params.Flags&dcbOutX != 0,
+			XON:    params.XonChar,
+			XOFF:   params.XoffChar,
+		},
+		HUPCL:          params.Flags&^dcbDTRControlDisableMask == dcbDTRControlEnable,
+		Exclusive:      p.cfg.Exclusive,
+		UUCPLockDir:    p.cfg.UUCPLockDir,
+		RestoreOnClose: p.internal.saved != nil,
+		RS485: RS485Config{
+			Enabled:   params.Flags&^dcbRTSControlDisableMask == dcbRTSControlToggle,
+			RTSOnSend: params.Flags&^dcbRTSControlDisableMask == dcbRTSControlToggle,
+		},
+	}
+	for k, v := range parityMap {
+		if v == params.Parity {
+			c.Parity = k
+		}
+	}
+	for k, v := range stopBitsMap {
+		if v == params.StopBits {
+			c.StopBits = k
+		}
+	}
+
+	p.mu.Lock()
+	t := *p.internal.timeouts
+	p.mu.Unlock()
+	switch {
+	case t.ReadIntervalTimeout == 0xFFFFFFFF && t.ReadTotalTimeoutConstant == 0:
+		c.ReadTimeout = 0
+	case t.ReadTotalTimeoutConstant == 0:
+		c.ReadTimeout = -1
+	default:
+		c.ReadTimeout = int(t.ReadTotalTimeoutConstant)
+	}
+	switch t.WriteTotalTimeoutConstant {
+	case 0:
+		c.WriteTimeout = -1
+	case 0xFFFFFFFF:
+		c.WriteTimeout = 0
+	default:
+		c.WriteTimeout = int(t.WriteTotalTimeoutConstant)
+	}
+	return c, nil
+}
+
+// RS485Config returns the RS-485 mode currently applied to the port.
+func (p *Port) RS485Config() (_ RS485Config, err error) {
+	defer p.wrapErr("rs485 config", &err)
+
+	if err := p.acquire(); err != nil {
+		return RS485Config{}, err
+	}
+	defer p.release()
+
+	params := &dcb{}
+	if err := getCommState(p.internal.handle, params); err != nil {
+		return RS485Config{}, newPortOSError(err)
+	}
+	if params.Flags&^dcbRTSControlDisableMask != dcbRTSControlToggle {
+		return RS485Config{}, nil
+	}
+	return RS485Config{Enabled: true, RTSOnSend: true}, nil
+}
+
+// blockingCall runs the blocking call f with a duplicate of the port handle in the background, so the call may be
+// abandoned when ctx is done, the deadline is exceeded or the port is closed, the port handle may be closed
+// meanwhile. The abandoned call is canceled with CancelIoEx. Zero deadline means no deadline.
+func (p *Port) blockingCall(ctx context.Context, deadline time.Time, f func(h syscall.Handle) error) error {
+	var dup windows.Handle
+	proc := windows.CurrentProcess()
+	err := windows.DuplicateHandle(proc, windows.Handle(p.internal.handle), proc, &dup, 0, false, windows.DUPLICATE_SAME_ACCESS)
+	if err != nil {
+		return newPortOSError(err)
+	}
+
+	// The duplicate is closed when both the call is done and it is not going to be canceled
+	res, released := make(chan error, 1), make(chan struct{})
+	defer close(released)
+	go func() {
+		res <- f(syscall.Handle(dup))
+		<-released
+		windows.CloseHandle(dup) //nolint:errcheck
+	}()
+
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case err = <-res:
+		if err != nil {
+			return newPortOSError(err)
+		}
+		return nil
+	case <-ctx.Done():
+		err = &PortError{code: OperationCanceled, wrapped: ctx.Err()}
+	case <-expired:
+		err = newDeadlineExceededError()
+	case <-p.internal.closed:
+		err = &PortError{code: PortClosed}
+	}
+	_ = windows.CancelIoEx(dup, nil) // nothing to do if the call is already done or not cancelable
+	return err
+}
+
+// WaitModemStatusChange blocks until any of the modem status lines selected by mask changes
+// and returns the new status.
+func (p *Port) WaitModemStatusChange(ctx context.Context, mask ModemStatusMask) (_ *ModemStatusBits, err error) {
+	defer p.wrapErr("wait modem status", &err)
+
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	w, err := p.newModemWatcher(mask)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+	if _, err = w.wait(ctx); err != nil {
+		return nil, err
+	}
+	return p.GetModemStatusBits()
+}
+
+// modemEvents maps the modem status lines to the comm events.
+var modemEvents = []struct {
+	line  ModemStatusMask
+	event uint32
+}{
+	{line: ModemCTS, event: evCts},
+	{line: ModemDSR, event: evDsr},
+	{line: ModemRI, event: evRing},
+	{line: ModemDCD, event: evRlsd},
+}
+
+// modemWatcher detects the modem status lines transitions since the previous wait call, the comm events mask
+// is kept set until close, so the events occurred between the wait calls are not lost.
+type modemWatcher struct {
+	p          *Port
+	events     uint32
+	overlapped *syscall.Overlapped
+}
+
+func (p *Port) newModemWatcher(mask ModemStatusMask) (*modemWatcher, error) {
+	w := &modemWatcher{p: p}
+	for _, m := range modemEvents {
+		if mask.orAll()&m.line != 0 {
+			w.events |= m.event
+		}
+	}
+
+	var err error
+	if w.overlapped, err = createOverlappedStruct(); err != nil {
+		return nil, newPortOSError(err)
+	}
+	if err = setCommMask(p.internal.handle, evErr|w.events); err != nil {
+		syscall.CloseHandle(w.overlapped.HEvent) //nolint:errcheck
+		return nil, newPortOSError(err)
+	}
+	return w, nil
+}
+
+// wait blocks until any of the selected lines transitions and returns the lines transitioned since
+// the previous call. A line may be back to its previous level, e.g. after a short pulse.
+func (w *modemWatcher) wait(ctx context.Context) (ModemStatusMask, error) {
+	if err := w.p.acquire(); err != nil {
+		return 0, err
+	}
+	defer w.p.release()
+
+	h := w.p.internal.handle
+	for {
+		var occurred, n uint32
+		err := waitCommEvent(h, &occurred, w.overlapped)
+		if err != nil && err != syscall.ERROR_IO_PENDING {
+			return 0, newPortOSError(err)
+		}
+		stop := w.p.internal.cancelOnDone(ctx, w.overlapped, &deadline{}, nil)
+		err = getOverlappedResult(h, w.overlapped, &n, true)
+		stop()
+		if w.p.internal.isClosed() {
+			return 0, &PortError{code: PortClosed}
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return 0, &PortError{code: OperationCanceled, wrapped: ctxErr}
+		}
+		if err != nil {
+			return 0, newPortOSError(err)
+		}
+
+		var changed ModemStatusMask
+		for _, m := range modemEvents {
+			if occurred&w.events&m.event != 0 {
+				changed |= m.line
+			}
+		}
+		if changed != 0 {
+			return changed, nil
+		}
+	}
+}
+
+func (w *modemWatcher) close() {
+	if w.p.acquire() == nil {
+		_ = setCommMask(w.p.internal.handle, evErr)
+		w.p.release()
+	}
+	syscall.CloseHandle(w.overlapped.HEvent) //nolint:errcheck
+}
+
+// Counters returns the serial line error counters accumulated from ClearCommError results
+// and the number of characters read and written. Modem status lines transitions are not available.
+func (p *Port) Counters() (_ *Counters, err error) {
+	defer p.wrapErr("counters", &err)
+
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+	defer p.release()
+
+	var stat comstat
+	if err := p.retrieveCommStatus(&stat); err != nil {
+		return nil, newPortOSError(err)
+	}
+
+	p.internal.countersMu.Lock()
+	defer p.internal.countersMu.Unlock()
+	c := p.internal.counters
+	return &c, nil
+}
+
+// retrieveCommStatus retrieves the port status and accumulates the reported errors into the counters.
+func (p *Port) retrieveCommStatus(stat *comstat) error {
+	var errs uint32
+	if err := clearCommError(p.internal.handle, &errs, stat); err != nil {
+		return err
+	}
+	if errs == 0 {
+		return nil
+	}
+
+	p.internal.countersMu.Lock()
+	defer p.internal.countersMu.Unlock()
+	c := &p.internal.counters
+	if errs&ceFrame != 0 {
+		c.Frame++
+	}
+	if errs&ceOverrun != 0 {
+		c.Overrun++
+	}
+	if errs&ceRxparity != 0 {
+		c.Parity++
+	}
+	if errs&ceBreak != 0 {
+		c.Break++
+	}
+	if errs&ceRxover != 0 {
+		c.BufferOverrun++
+	}
+	return nil
+}
+
+func (p *Port) countBytes(rx, tx uint32) {
+	p.internal.countersMu.Lock()
+	defer p.internal.countersMu.Unlock()
+	p.internal.counters.RX += rx
+	p.internal.counters.TX += tx
+}
+
 func (p *Port) setReadTimeoutValues(t int) {
 	switch {
 	case t < 0: // Block until the buffer is full.
@@ -394,48 +973,91 @@ func (p *Port) setWriteTimeoutValues(t int) {
 	}
 }
 
+func (p *Port) saveState() (*savedState, error) {
+	state := &savedState{}
+	if err := getCommState(p.internal.handle, &state.params); err != nil {
+		return nil, wrapOSError(InvalidSerialPort, err)
+	}
+	if err := getCommTimeouts(p.internal.handle, &state.timeouts); err != nil {
+		return nil, wrapOSError(InvalidSerialPort, err)
+	}
+	return state, nil
+}
+
+// restoreState restores the device state saved on open (if any).
+func (p *Port) restoreState() error {
+	state := p.internal.saved
+	if state == nil {
+		return nil
+	}
+	p.internal.saved = nil
+
+	return multierr.Append(
+		setCommState(p.internal.handle, &state.params),
+		setCommTimeouts(p.internal.handle, &state.timeouts),
+	)
+}
+
 func (p *Port) reconfigure() error {
 	if err := setCommTimeouts(p.internal.handle, p.internal.timeouts); err != nil {
-		p.Close()
-		return &PortError{code: InvalidSerialPort, wrapped: err}
+		return wrapOSError(InvalidSerialPort, err)
 	}
 	if err := setCommMask(p.internal.handle, evErr); err != nil {
-		p.Close()
-		return &PortError{code: InvalidSerialPort, wrapped: err}
+		return wrapOSError(InvalidSerialPort, err)
 	}
 	params := &dcb{}
 	if err := getCommState(p.internal.handle, params); err != nil {
-		p.Close()
-		return &PortError{code: InvalidSerialPort, wrapped: err}
+		return wrapOSError(InvalidSerialPort, err)
 	}
 	params.Flags &= dcbRTSControlDisableMask
-	params.Flags |= dcbRTSControlEnable
 	params.Flags &= dcbDTRControlDisableMask
-	if p.hupcl {
-		params.Flags |= dcbDTRControlEnable
-	}
 	params.Flags &^= dcbOutXCTSFlow
 	params.Flags &^= dcbOutXDSRFlow
+	switch {
+	case p.cfg.RS485.Enabled:
+		params.Flags |= dcbRTSControlToggle
+	case p.cfg.FlowControl == RTSCTSFlowControl:
+		params.Flags |= dcbRTSControlHandshake
+		params.Flags |= dcbOutXCTSFlow
+	default:
+		params.Flags |= dcbRTSControlEnable
+	}
+	switch {
+	case p.cfg.FlowControl == DTRDSRFlowControl:
+		params.Flags |= dcbDTRControlHandshake
+		params.Flags |= dcbOutXDSRFlow
+	case p.cfg.HUPCL:
+		params.Flags |= dcbDTRControlEnable
+	}
 	params.Flags &^= dcbDSRSensitivity
 	params.Flags |= dcbTXContinueOnXOFF
 	params.Flags &^= dcbInX
+	if p.cfg.SoftwareFlowControl.Input {
+		params.Flags |= dcbInX
+	}
 	params.Flags &^= dcbOutX
+	if p.cfg.SoftwareFlowControl.Output {
+		params.Flags |= dcbOutX
+	}
 	params.Flags &^= dcbErrorChar
 	params.Flags &^= dcbNull
 	params.Flags &^= dcbAbortOnError
 	params.XonLim = 2048
 	params.XoffLim = 512
-	params.XonChar = 17  // DC1
-	params.XoffChar = 19 // C3
+	params.XonChar = p.cfg.SoftwareFlowControl.xon()
+	params.XoffChar = p.cfg.SoftwareFlowControl.xoff()
+	if params.XonChar == params.XoffChar {
+		// Allowed while the flow control is disabled, but rejected by SetCommState()
+		params.XonChar, params.XoffChar = DefaultXON, DefaultXOFF
+	}
 
-	params.BaudRate = uint32(p.baudRate)
-	params.ByteSize = byte(p.dataBits)
-	params.Parity = parityMap[p.parity]
-	params.StopBits = stopBitsMap[p.stopBits]
+	params.BaudRate = uint32(p.cfg.BaudRate)
+	params.ByteSize = byte(p.cfg.DataBits)
+	params.Parity = parityMap[p.cfg.Parity]
+	params.StopBits = stopBitsMap[p.cfg.StopBits]
 
 	if err := setCommState(p.internal.handle, params); err != nil {
-		p.Close()
-		return &PortError{code: InvalidSerialPort, wrapped: err}
+		return wrapOSError(InvalidSerialPort, err)
 	}
 	return nil
 }
@@ -474,3 +1096,66 @@ func GetPortsList() ([]string, error) {
 func isHandleValid(h syscall.Handle) bool {
 	return h != syscall.InvalidHandle
 }
+
+// cancelOnDone cancels the pending overlapped operation when ctx is done, the deadline d is exceeded or the port is closed.
+// Changes of the deadline are signaled via changed channel. Returned function must be called to release resources.
+func (p *port) cancelOnDone(ctx context.Context, o *syscall.Overlapped, d *deadline, changed <-chan struct{}) (stop func()) {
+	h := p.handle
+	stopCh, doneCh := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		for {
+			var timer *time.Timer
+			var expired <-chan time.Time
+			if t := d.get(); !t.IsZero() {
+				timer = time.NewTimer(time.Until(t))
+				expired = timer.C
+			}
+			cancel := false
+			select {
+			case <-ctx.Done():
+				cancel = true
+			case <-expired:
+				cancel = true
+			case <-p.closed:
+				cancel = true
+			case <-changed:
+			case <-stopCh:
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			if cancel {
+				_ = cancelIoEx(h, o) // ERROR_NOT_FOUND if already completed
+				return
+			}
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+		}
+	}()
+	return func() {
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// isClosed reports whether the port is closed.
+func (p *port) isClosed() bool {
+	select {
+	case <-p.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// notify sends non-blocking signal to the buffered channel.
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -96,6 +96,17 @@ type dcb struct {
 	wReserved1 uint16
 }
 
+func (d *dcb) flowControl() FlowControl {
+	switch {
+	case d.Flags&dcbOutXCTSFlow != 0:
+		return RTSCTSFlowControl
+	case d.Flags&dcbOutXDSRFlow != 0:
+		return DTRDSRFlowControl
+	default:
+		return NoFlowControl
+	}
+}
+
 //sys getCommState(handle syscall.Handle, dcb *dcb) (err error) = GetCommState
 
 //sys setCommState(handle syscall.Handle, dcb *dcb) (err error) = SetCommState
@@ -108,6 +119,8 @@ type commTimeouts struct {
 	WriteTotalTimeoutConstant   uint32
 }
 
+//sys getCommTimeouts(handle syscall.Handle, timeouts *commTimeouts) (err error) = GetCommTimeouts
+
 //sys setCommTimeouts(handle syscall.Handle, timeouts *commTimeouts) (err error) = SetCommTimeouts
 
 const (
@@ -123,6 +136,10 @@ const (
 
 //sys escapeCommFunction(handle syscall.Handle, function uint32) (res bool) = EscapeCommFunction
 
+//sys setCommBreak(handle syscall.Handle) (err error) = SetCommBreak
+
+//sys clearCommBreak(handle syscall.Handle) (err error) = ClearCommBreak
+
 const (
 	msCTSOn  = 0x0010
 	msDSROn  = 0x0020
@@ -146,12 +163,18 @@ const (
 
 //sys setCommMask(handle syscall.Handle, mask uint32) (err error) = SetCommMask
 
+//sys waitCommEvent(handle syscall.Handle, mask *uint32, overlapped *syscall.Overlapped) (err error) = WaitCommEvent
+
 //sys createEvent(eventAttributes *uint32, manualReset bool, initialState bool, name *uint16) (handle syscall.Handle, err error) = CreateEventW
 
 //sys resetEvent(handle syscall.Handle) (err error) = ResetEvent
 
 //sys getOverlappedResult(handle syscall.Handle, overlapEvent *syscall.Overlapped, n *uint32, wait bool) (err error) = GetOverlappedResult
 
+//sys cancelIoEx(handle syscall.Handle, overlapped *syscall.Overlapped) (err error) = CancelIoEx
+
+//sys flushFileBuffers(handle syscall.Handle) (err error) = FlushFileBuffers
+
 const (
 	purgeRxAbort uint32 = 0x0002
 	purgeRxClear        = 0x0008
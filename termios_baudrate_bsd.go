@@ -23,3 +23,12 @@ func (s *settings) setBaudrate(speed int) error {
 	s.termios.Ospeed = toTermiosSpeedType(baudrate)
 	return nil
 }
+
+func (s *settings) baudrate() int {
+	return int(s.termios.Ospeed)
+}
+
+func isStandardBaudrate(r int) bool {
+	_, ok := baudrateMap[r]
+	return ok
+}
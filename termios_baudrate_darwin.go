@@ -12,3 +12,7 @@ func (s *settings) setBaudrate(speed int) error {
 	s.specificBaudrate = speed
 	return nil
 }
+
+func (s *settings) baudrate() int {
+	return s.specificBaudrate
+}
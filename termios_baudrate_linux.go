@@ -30,3 +30,16 @@ func (s *settings) setBaudrate(r int) error {
 	}
 	return nil
 }
+
+func (s *settings) baudrate() int {
+	if s.termios.Cflag&unix.BOTHER == unix.BOTHER {
+		return int(s.termios.Ospeed)
+	}
+	rate := s.termios.Cflag & unix.CBAUD
+	for r, b := range baudrateMap {
+		if r != 0 && b == rate {
+			return r
+		}
+	}
+	return 0
+}
@@ -51,6 +51,22 @@ func (s *settings) setParity(parity Parity) error {
 	return nil
 }
 
+func (s *settings) parity() Parity {
+	switch {
+	case s.termios.Cflag&unix.PARENB == 0:
+		return NoParity
+	case tcCMSPAR != 0 && s.termios.Cflag&tcCMSPAR != 0:
+		if s.termios.Cflag&unix.PARODD != 0 {
+			return MarkParity
+		}
+		return SpaceParity
+	case s.termios.Cflag&unix.PARODD != 0:
+		return OddParity
+	default:
+		return EvenParity
+	}
+}
+
 func (s *settings) setDataBits(bits int) error {
 	databits, ok := databitsMap[bits]
 	if !ok {
@@ -63,6 +79,16 @@ func (s *settings) setDataBits(bits int) error {
 	return nil
 }
 
+func (s *settings) dataBits() int {
+	size := s.termios.Cflag & unix.CSIZE
+	for bits, v := range databitsMap {
+		if bits != 0 && v == size {
+			return bits
+		}
+	}
+	return 0
+}
+
 func (s *settings) setStopBits(bits StopBits) error {
 	switch bits {
 	case OneStopBit:
@@ -77,12 +103,89 @@ func (s *settings) setStopBits(bits StopBits) error {
 	return nil
 }
 
-func (s *settings) setCtsRts(enable bool) {
-	if enable {
+func (s *settings) stopBits() StopBits {
+	if s.termios.Cflag&unix.CSTOPB != 0 {
+		return TwoStopBits
+	}
+	return OneStopBit
+}
+
+func (s *settings) hupcl() bool {
+	return s.termios.Cflag&unix.HUPCL != 0
+}
+
+func (s *settings) setFlowControl(fc FlowControl) error {
+	switch fc {
+	case NoFlowControl:
+		s.termios.Cflag &^= tcCRTSCTS
+		s.termios.Cflag &^= tcCDTRDSR
+	case RTSCTSFlowControl:
+		s.termios.Cflag &^= tcCDTRDSR
 		s.termios.Cflag |= tcCRTSCTS
-	} else {
+	case DTRDSRFlowControl:
+		if tcCDTRDSR == 0 {
+			return &PortError{code: InvalidFlowControl}
+		}
 		s.termios.Cflag &^= tcCRTSCTS
+		s.termios.Cflag |= tcCDTRDSR
+	default:
+		return &PortError{code: InvalidFlowControl}
+	}
+	return nil
+}
+
+func (s *settings) flowControl() FlowControl {
+	switch {
+	case s.termios.Cflag&tcCRTSCTS == tcCRTSCTS:
+		return RTSCTSFlowControl
+	case tcCDTRDSR != 0 && s.termios.Cflag&tcCDTRDSR == tcCDTRDSR:
+		return DTRDSRFlowControl
+	default:
+		return NoFlowControl
+	}
+}
+
+func (s *settings) setSoftwareFlowControl(fc SoftwareFlowControl) {
+	if fc.Input {
+		s.termios.Iflag |= unix.IXOFF
+	} else {
+		s.termios.Iflag &^= unix.IXOFF
+	}
+	if fc.Output {
+		s.termios.Iflag |= unix.IXON
+	} else {
+		s.termios.Iflag &^= unix.IXON
+	}
+	if fc.Any {
+		s.termios.Iflag |= unix.IXANY
+	} else {
+		s.termios.Iflag &^= unix.IXANY
 	}
+	s.termios.Cc[unix.VSTART] = fc.xon()
+	s.termios.Cc[unix.VSTOP] = fc.xoff()
+}
+
+func (s *settings) softwareFlowControl() SoftwareFlowControl {
+	return SoftwareFlowControl{
+		Input:  s.termios.Iflag&unix.IXOFF != 0,
+		Output: s.termios.Iflag&unix.IXON != 0,
+		Any:    s.termios.Iflag&unix.IXANY != 0,
+		XON:    s.termios.Cc[unix.VSTART],
+		XOFF:   s.termios.Cc[unix.VSTOP],
+	}
+}
+
+// setLineErrorReporting marks the break condition and erroneous characters in the input,
+// must follow setRawMode().
+func (s *settings) setLineErrorReporting(enable bool) {
+	if enable {
+		s.termios.Iflag |= unix.PARMRK
+		s.termios.Iflag |= unix.INPCK
+	}
+}
+
+func (s *settings) lineErrorReporting() bool {
+	return s.termios.Iflag&unix.PARMRK != 0
 }
 
 func (s *settings) setRawMode(hupcl bool) {
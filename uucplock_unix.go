@@ -0,0 +1,185 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+//go:build linux || darwin || freebsd || openbsd
+
+package serial
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// uucpLockAttempts limits the stale lock removal retries.
+const uucpLockAttempts = 3
+
+// linkUUCPLock creates the lock file link, replaced in tests.
+var linkUUCPLock = os.Link
+
+// uucpLock is the UUCP style LCK..<device> lock file owned by the current process.
+type uucpLock struct {
+	path string
+	pid  int
+}
+
+// acquireUUCPLock creates the UUCP lock file for the device in dir (DefaultUUCPLockDir if empty).
+// The lock held by the live process is reported as PortBusy wrapping LockHolder,
+// the stale lock of the dead process is removed.
+func acquireUUCPLock(dir, device string) (*uucpLock, error) {
+	if dir == "" {
+		dir = DefaultUUCPLockDir
+	}
+	l := &uucpLock{path: filepath.Join(dir, uucpLockName(device)), pid: os.Getpid()}
+
+	// The lock file is created atomically by linking the completely written temporary file,
+	// the name is unique, so the locks of different devices may be acquired concurrently
+	tmp, err := writeUUCPLockTemp(dir, l.pid)
+	if err != nil {
+		return nil, newUUCPLockError(err)
+	}
+	defer os.Remove(tmp)
+
+	for i := 0; i < uucpLockAttempts; i++ {
+		err := os.Link(tmp, l.path)
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, newUUCPLockError(err)
+		}
+
+		pid, err := readUUCPLock(l.path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // removed meanwhile
+			}
+			return nil, newUUCPLockError(err)
+		}
+		if pid > 0 && processExists(pid) {
+			return nil, &PortError{code: PortBusy, wrapped: &LockHolder{PID: pid, Lock: "uucp"}}
+		}
+		if err = removeStaleUUCPLock(l.path, tmp+".stale", pid); err != nil {
+			var busy *PortError
+			if errors.As(err, &busy) {
+				return nil, busy
+			}
+			return nil, newUUCPLockError(err)
+		}
+	}
+	return nil, &PortError{code: PortBusy, wrapped: &LockHolder{Lock: "uucp"}}
+}
+
+// writeUUCPLockTemp writes the lock file content for the process to the new temporary file in dir.
+func writeUUCPLockTemp(dir string, pid int) (string, error) {
+	f, err := os.CreateTemp(dir, "LTMP.")
+	if err != nil {
+		return "", err
+	}
+	_, err = fmt.Fprintf(f, "%10d\n", pid)
+	if err == nil {
+		err = f.Chmod(0o644) //nolint:gomnd // the lock is read by the other processes
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// removeStaleUUCPLock removes the lock file found stale with the pid. The file is renamed away first and checked
+// again, the fresh lock created by another process meanwhile is put back. The renamed fresh lock which can not be
+// put back is kept as stale, PortBusy is returned if yet another lock has been created before that.
+func removeStaleUUCPLock(path, stale string, pid int) error {
+	if err := os.Rename(path, stale); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // removed meanwhile
+		}
+		return err
+	}
+
+	if renamed, err := readUUCPLock(stale); err == nil && renamed != pid {
+		// Not the stale lock, restore it
+		if err = linkUUCPLock(stale, path); err != nil {
+			if errors.Is(err, fs.ErrExist) {
+				// The renamed lock may still be valid, removing it would release the port owned by its process
+				return &PortError{code: PortBusy, wrapped: &LockHolder{PID: renamed, Lock: "uucp"}}
+			}
+			return err
+		}
+	}
+	return os.Remove(stale)
+}
+
+// release removes the lock file if it is still owned by the current process, does nothing on nil lock.
+func (l *uucpLock) release() error {
+	if l == nil {
+		return nil
+	}
+	pid, err := readUUCPLock(l.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return newUUCPLockError(err)
+	}
+	if pid != l.pid {
+		return nil // not ours anymore
+	}
+	if err = os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return newUUCPLockError(err)
+	}
+	return nil
+}
+
+// uucpLockName returns the lock file name for the device, the device symlinks are resolved
+// and the slashes of the name relative to /dev are replaced with underscores e.g. LCK..pts_3.
+func uucpLockName(device string) string {
+	if p, err := filepath.EvalSymlinks(device); err == nil {
+		device = p
+	}
+	device = strings.TrimPrefix(filepath.Clean(device), devicesBasePath+"/")
+	return "LCK.." + strings.ReplaceAll(device, "/", "_")
+}
+
+// readUUCPLock returns the PID stored in the lock file, ASCII (HDB) or binary format,
+// zero PID means the malformed lock.
+func readUUCPLock(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+		return pid, nil
+	}
+	if len(data) == 4 { //nolint:gomnd
+		return int(int32(binary.LittleEndian.Uint32(data))), nil
+	}
+	return 0, nil
+}
+
+func processExists(pid int) bool {
+	err := unix.Kill(pid, 0)
+	return err == nil || errors.Is(err, unix.EPERM)
+}
+
+func newUUCPLockError(err error) *PortError {
+	if errors.Is(err, fs.ErrPermission) {
+		return &PortError{code: PermissionDenied, wrapped: err}
+	}
+	return newPortOSError(err)
+}
@@ -0,0 +1,94 @@
+//go:build linux || darwin || freebsd || openbsd
+
+package serial
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveStaleUUCPLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "LCK..ttyUSB0")
+	stale := filepath.Join(dir, "LTMP.stale")
+
+	// Stale lock is removed
+	require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf("%10d\n", 99999999)), 0o644))
+	require.NoError(t, removeStaleUUCPLock(path, stale, 99999999))
+	assert.NoFileExists(t, path)
+	assert.NoFileExists(t, stale)
+
+	// Already removed
+	require.NoError(t, removeStaleUUCPLock(path, stale, 99999999))
+
+	// Fresh lock created by another process after the stale one has been read is kept
+	fresh := fmt.Sprintf("%10d\n", os.Getpid())
+	require.NoError(t, os.WriteFile(path, []byte(fresh), 0o644))
+	require.NoError(t, removeStaleUUCPLock(path, stale, 99999999))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, fresh, string(data))
+	assert.NoFileExists(t, stale)
+}
+
+func TestRemoveStaleUUCPLock_Race(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "LCK..ttyUSB0")
+	stale := filepath.Join(dir, "LTMP.stale")
+
+	// Fresh lock is renamed away and yet another lock is created before it is put back
+	fresh := fmt.Sprintf("%10d\n", os.Getpid())
+	another := fmt.Sprintf("%10d\n", os.Getppid())
+	link := linkUUCPLock
+	t.Cleanup(func() { linkUUCPLock = link })
+	linkUUCPLock = func(oldname, newname string) error {
+		require.NoError(t, os.WriteFile(newname, []byte(another), 0o644))
+		return link(oldname, newname)
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(fresh), 0o644))
+	err := removeStaleUUCPLock(path, stale, 99999999)
+	var portErr *PortError
+	require.ErrorAs(t, err, &portErr)
+	assert.Equal(t, PortBusy, portErr.Code())
+	var holder *LockHolder
+	require.ErrorAs(t, err, &holder)
+	assert.Equal(t, os.Getpid(), holder.PID)
+
+	// Neither lock is removed
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, another, string(data))
+	data, err = os.ReadFile(stale)
+	require.NoError(t, err)
+	assert.Equal(t, fresh, string(data))
+}
+
+func TestAcquireUUCPLock_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+
+	const n = 32
+	errs := make(chan error, n)
+	locks := make(chan *uucpLock, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			l, err := acquireUUCPLock(dir, fmt.Sprintf("/dev/ttyUSB%d", i))
+			errs <- err
+			locks <- l
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		require.NoError(t, (<-locks).release())
+	}
+
+	// No temporary files are left
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,139 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PortEventType is the kind of the PortEvent.
+type PortEventType int
+
+const (
+	// PortAdded the port has appeared, e.g. USB adapter has been plugged in.
+	PortAdded PortEventType = iota + 1
+	// PortRemoved the port has disappeared, e.g. USB adapter has been unplugged.
+	PortRemoved
+)
+
+func (t PortEventType) String() string {
+	switch t {
+	case PortAdded:
+		return "added"
+	case PortRemoved:
+		return "removed"
+	}
+	return fmt.Sprintf("PortEventType(%d)", t)
+}
+
+// PortEvent is the serial port arrival or removal reported by Watch().
+type PortEvent struct {
+	Type PortEventType
+	Port *PortDetails // Details of the port, the ones known before the removal for PortRemoved
+}
+
+// watchPollInterval is the interval of the ports list polling where no hotplug notifications are available,
+// replaced in tests.
+var watchPollInterval = time.Second
+
+// pollPorts reports the difference between the subsequent port lists returned by list every interval.
+// The ports present on start are not reported, the error of the first list call is returned.
+func pollPorts(ctx context.Context, list func() ([]*PortDetails, error), interval time.Duration) (<-chan PortEvent, error) {
+	ports, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	t := newPortsTracker(ports)
+	go func() {
+		defer close(t.events)
+		t.poll(ctx, list, interval)
+	}()
+	return t.events, nil
+}
+
+// portsTracker reports the changes of the known ports.
+type portsTracker struct {
+	known  map[string]*PortDetails
+	events chan PortEvent
+}
+
+func newPortsTracker(ports []*PortDetails) *portsTracker {
+	return &portsTracker{known: portsByName(ports), events: make(chan PortEvent)}
+}
+
+// poll reports the difference between the known ports and the ones returned by list every interval
+// until ctx is done.
+func (t *portsTracker) poll(ctx context.Context, list func() ([]*PortDetails, error), interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ports, err := list()
+		if err != nil {
+			continue // try again next time
+		}
+		if !t.update(ctx, ports) {
+			return
+		}
+	}
+}
+
+// update reports the events turning the known ports into the current ones, false is returned if ctx is done before.
+func (t *portsTracker) update(ctx context.Context, current []*PortDetails) bool {
+	var diff []PortEvent
+	diff, t.known = diffPorts(t.known, current)
+	return sendPortEvents(ctx, t.events, diff...)
+}
+
+func portsByName(ports []*PortDetails) map[string]*PortDetails {
+	m := make(map[string]*PortDetails, len(ports))
+	for _, d := range ports {
+		m[d.Name] = d
+	}
+	return m
+}
+
+// diffPorts returns the events turning the known ports into the current ones, removals first,
+// and the current ports by name.
+func diffPorts(known map[string]*PortDetails, current []*PortDetails) ([]PortEvent, map[string]*PortDetails) {
+	next := portsByName(current)
+
+	var events []PortEvent
+	for name, d := range known {
+		if next[name] == nil {
+			events = append(events, PortEvent{Type: PortRemoved, Port: d})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Port.Name < events[j].Port.Name })
+	for _, d := range current {
+		if known[d.Name] == nil {
+			events = append(events, PortEvent{Type: PortAdded, Port: d})
+		}
+	}
+	return events, next
+}
+
+// sendPortEvents sends the events, false is returned if ctx is done before.
+func sendPortEvents(ctx context.Context, ch chan<- PortEvent, events ...PortEvent) bool {
+	for _, ev := range events {
+		select {
+		case ch <- ev:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
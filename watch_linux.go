@@ -0,0 +1,201 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+package serial
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+
+	"go.uber.org/multierr"
+	"golang.org/x/sys/unix"
+
+	"github.com/albenik/go-serial/v2/unixutils"
+)
+
+// Watch reports the serial ports arrival and removal until ctx is done, the returned channel is closed then.
+// The ports present when Watch is called are not reported, call GetDetailedPortsList() after Watch() returns
+// to get them without missing any event.
+//
+// The kernel uevents are received from the netlink socket, the ports list is polled every second if the socket
+// is not available (e.g. in a network namespace) or fails. The events are reported before udev creates
+// the persistent symlinks, so PortDetails.IDs and PortDetails.Paths are usually empty for PortAdded.
+func Watch(ctx context.Context) (<-chan PortEvent, error) {
+	src, err := newNetlinkUevents()
+	if err != nil {
+		return pollPorts(ctx, GetDetailedPortsList, watchPollInterval)
+	}
+	return defaultEnumerator.watch(ctx, src)
+}
+
+// uevent is the kernel object event, e.g. {"ACTION": "add", "SUBSYSTEM": "tty", "DEVNAME": "ttyUSB0", ...}.
+type uevent map[string]string
+
+// ueventSource receives the kernel uevents, the synthetic ones are injected in tests.
+type ueventSource interface {
+	// receive blocks until the next uevent is received or ctx is done. Lost events are reported with unix.ENOBUFS.
+	receive(ctx context.Context) (uevent, error)
+	close() error
+}
+
+// watch reports the ports found by the uevents received from src, the ports list is read again if events are lost.
+// The ports list is polled if the uevents can not be received anymore.
+func (e sysfsEnumerator) watch(ctx context.Context, src ueventSource) (<-chan PortEvent, error) {
+	e = e.resolved()
+	ports, err := e.ports()
+	if err != nil {
+		return nil, multierr.Append(err, src.close())
+	}
+
+	t := newPortsTracker(ports)
+	go func() {
+		defer close(t.events)
+
+		if err := e.receivePorts(ctx, src, t); err != nil && ctx.Err() == nil {
+			t.poll(ctx, e.ports, watchPollInterval)
+		}
+	}()
+	return t.events, nil
+}
+
+// receivePorts reports the ports changes found by the uevents received from src until ctx is done
+// or the receiving fails, src is closed then.
+func (e sysfsEnumerator) receivePorts(ctx context.Context, src ueventSource, t *portsTracker) error {
+	defer src.close()
+
+	for {
+		ev, err := src.receive(ctx)
+		switch {
+		case errors.Is(err, unix.ENOBUFS):
+			ports, err := e.ports()
+			if err != nil {
+				continue
+			}
+			if !t.update(ctx, ports) {
+				return ctx.Err()
+			}
+			continue
+		case err != nil:
+			return err
+		case ev["SUBSYSTEM"] != "tty":
+			continue
+		}
+
+		switch ev["ACTION"] {
+		case "add":
+			d := e.port(filepath.Base(ev["DEVPATH"]), e.serialLinks())
+			if d == nil || t.known[d.Name] != nil {
+				continue // not a serial port or already reported
+			}
+			t.known[d.Name] = d
+			if !sendPortEvents(ctx, t.events, PortEvent{Type: PortAdded, Port: d}) {
+				return ctx.Err()
+			}
+		case "remove":
+			name := ev["DEVNAME"]
+			if name == "" {
+				name = filepath.Base(ev["DEVPATH"])
+			}
+			d := t.known[filepath.Join(e.devRoot, name)]
+			if d == nil {
+				continue
+			}
+			delete(t.known, d.Name)
+			if !sendPortEvents(ctx, t.events, PortEvent{Type: PortRemoved, Port: d}) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// ueventKernelGroup is the netlink multicast group of the uevents sent by the kernel (udev resends them to group 2).
+const ueventKernelGroup = 1
+
+// netlinkUevents receives the kernel uevents from the NETLINK_KOBJECT_UEVENT socket.
+type netlinkUevents struct {
+	fd   int
+	wake wakePipe
+	buf  []byte
+}
+
+func newNetlinkUevents() (*netlinkUevents, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+	if err = unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: ueventKernelGroup}); err != nil {
+		return nil, multierr.Append(err, unix.Close(fd))
+	}
+	w, err := newWakePipe()
+	if err != nil {
+		return nil, multierr.Append(err, unix.Close(fd))
+	}
+	return &netlinkUevents{fd: fd, wake: w, buf: make([]byte, 16*1024)}, nil
+}
+
+func (s *netlinkUevents) receive(ctx context.Context) (uevent, error) {
+	stop := s.wake.wakeOnDone(ctx)
+	defer stop()
+
+	fds := unixutils.NewFDSet(s.fd, s.wake.r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res, err := unixutils.Select(fds, nil, nil, -1)
+		if err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return nil, err
+		}
+		if res.IsReadable(s.wake.r) {
+			s.wake.drain()
+			continue
+		}
+
+		n, from, err := unix.Recvfrom(s.fd, s.buf, 0)
+		if err != nil {
+			if errors.Is(err, unix.EINTR) || errors.Is(err, unix.EAGAIN) {
+				continue
+			}
+			return nil, err
+		}
+		// Only the kernel messages are trusted
+		if nl, ok := from.(*unix.SockaddrNetlink); !ok || nl.Pid != 0 {
+			continue
+		}
+		if ev := parseUevent(s.buf[:n]); ev != nil {
+			return ev, nil
+		}
+	}
+}
+
+func (s *netlinkUevents) close() error {
+	return multierr.Append(unix.Close(s.fd), s.wake.close())
+}
+
+// parseUevent parses the kernel uevent message: "ACTION@DEVPATH" header followed by the KEY=VALUE pairs,
+// all separated by zero bytes. Nil is returned for malformed messages.
+func parseUevent(b []byte) uevent {
+	fields := bytes.Split(b, []byte{0})
+	if len(fields) < 2 || !bytes.Contains(fields[0], []byte{'@'}) {
+		return nil
+	}
+	ev := make(uevent, len(fields)-1)
+	for _, f := range fields[1:] {
+		if k, v, ok := bytes.Cut(f, []byte{'='}); ok {
+			ev[string(k)] = string(v)
+		}
+	}
+	if ev["ACTION"] == "" || ev["DEVPATH"] == "" {
+		return nil
+	}
+	return ev
+}
@@ -0,0 +1,206 @@
+package serial
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+// fakeUevents injects the synthetic uevents, nil event means lost events (ENOBUFS).
+type fakeUevents struct {
+	events chan uevent
+	errs   chan error
+	closed atomic.Bool
+}
+
+func newFakeUevents() *fakeUevents {
+	return &fakeUevents{events: make(chan uevent), errs: make(chan error)}
+}
+
+func (s *fakeUevents) receive(ctx context.Context) (uevent, error) {
+	select {
+	case ev := <-s.events:
+		if ev == nil {
+			return nil, unix.ENOBUFS
+		}
+		return ev, nil
+	case err := <-s.errs:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *fakeUevents) close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+func nextEvent(t *testing.T, events <-chan PortEvent) PortEvent {
+	t.Helper()
+
+	select {
+	case ev, ok := <-events:
+		require.True(t, ok, "events channel closed")
+		return ev
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event")
+	}
+	return PortEvent{}
+}
+
+func TestSysfsEnumerator_Watch(t *testing.T) {
+	e := sysfsFixture(t)
+	dev := func(name string) string { return filepath.Join(e.devRoot, name) }
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := newFakeUevents()
+	events, err := e.watch(ctx, src)
+	require.NoError(t, err)
+
+	// Second FTDI adapter plugged in
+	const ftdi2 = fixtureUSB + "/1-4/1-4:1.0/ttyUSB1"
+	makeTree(t, e.sysRoot, map[string]string{
+		fixtureUSB + "/1-4/idVendor":                 "0403\n",
+		fixtureUSB + "/1-4/idProduct":                "6015\n",
+		fixtureUSB + "/1-4/serial":                   "D4E5F6\n",
+		fixtureUSB + "/1-4/1-4:1.0/bInterfaceNumber": "00\n",
+		ftdi2 + "/driver":                            "->../../../../../../../bus/usb-serial/drivers/ftdi_sio",
+		ftdi2 + "/subsystem":                         "->../../../../../../../bus/usb-serial",
+		ftdi2 + "/tty/ttyUSB1/uevent":                "MAJOR=188\nMINOR=1\nDEVNAME=ttyUSB1\n",
+		ftdi2 + "/tty/ttyUSB1/device":                "->../../../ttyUSB1",
+		"class/tty/ttyUSB1":                          "->../../" + ftdi2 + "/tty/ttyUSB1",
+	})
+	src.events <- uevent{"ACTION": "add", "DEVPATH": "/" + fixtureUSB + "/1-4", "SUBSYSTEM": "usb"}
+	src.events <- uevent{"ACTION": "add", "DEVPATH": "/" + ftdi2, "SUBSYSTEM": "usb-serial"}
+	src.events <- uevent{"ACTION": "add", "DEVPATH": "/" + ftdi2 + "/tty/ttyUSB1", "SUBSYSTEM": "tty", "DEVNAME": "ttyUSB1"}
+
+	ev := nextEvent(t, events)
+	assert.Equal(t, PortAdded, ev.Type)
+	assert.Equal(t, &PortDetails{
+		Name:         dev("ttyUSB1"),
+		Driver:       "ftdi_sio",
+		Bus:          "usb",
+		IsUSB:        true,
+		VID:          "0403",
+		PID:          "6015",
+		SerialNumber: "D4E5F6",
+		USBPath:      "1-4",
+	}, ev.Port)
+
+	// Repeated, virtual terminal and unknown events are ignored
+	src.events <- uevent{"ACTION": "add", "DEVPATH": "/" + ftdi2 + "/tty/ttyUSB1", "SUBSYSTEM": "tty", "DEVNAME": "ttyUSB1"}
+	src.events <- uevent{"ACTION": "add", "DEVPATH": "/" + fixtureTTY, "SUBSYSTEM": "tty", "DEVNAME": "tty0"}
+	src.events <- uevent{"ACTION": "remove", "DEVPATH": "/devices/virtual/tty/tty9", "SUBSYSTEM": "tty", "DEVNAME": "tty9"}
+	src.events <- uevent{"ACTION": "change", "DEVPATH": "/" + ftdi2 + "/tty/ttyUSB1", "SUBSYSTEM": "tty", "DEVNAME": "ttyUSB1"}
+
+	// Arduino unplugged, sysfs entries are gone before the event is received
+	require.NoError(t, os.RemoveAll(filepath.Join(e.sysRoot, fixtureUSB, "1-3")))
+	require.NoError(t, os.Remove(filepath.Join(e.sysRoot, "class/tty/ttyACM0")))
+	src.events <- uevent{"ACTION": "remove", "DEVPATH": "/" + fixtureACM + "/tty/ttyACM0", "SUBSYSTEM": "tty", "DEVNAME": "ttyACM0"}
+
+	ev = nextEvent(t, events)
+	assert.Equal(t, PortRemoved, ev.Type)
+	assert.Equal(t, dev("ttyACM0"), ev.Port.Name)
+	assert.Equal(t, "2341", ev.Port.VID)
+
+	// Events are lost, the ports list is read again
+	require.NoError(t, os.Remove(filepath.Join(e.sysRoot, "class/tty/ttyS2")))
+	require.NoError(t, os.Remove(filepath.Join(e.sysRoot, "class/tty/ttyUSB1")))
+	src.events <- nil
+
+	ev = nextEvent(t, events)
+	assert.Equal(t, PortRemoved, ev.Type)
+	assert.Equal(t, dev("ttyS2"), ev.Port.Name)
+	ev = nextEvent(t, events)
+	assert.Equal(t, PortRemoved, ev.Type)
+	assert.Equal(t, dev("ttyUSB1"), ev.Port.Name)
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok, "unexpected event")
+	case <-time.After(time.Second):
+		assert.Fail(t, "events channel is not closed")
+	}
+}
+
+func TestSysfsEnumerator_Watch_Fallback(t *testing.T) {
+	defer func(prev time.Duration) { watchPollInterval = prev }(watchPollInterval)
+	watchPollInterval = time.Millisecond
+
+	e := sysfsFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := newFakeUevents()
+	events, err := e.watch(ctx, src)
+	require.NoError(t, err)
+
+	// The ports list is polled once the uevents receiving fails
+	require.NoError(t, os.Remove(filepath.Join(e.sysRoot, "class/tty/ttyACM0")))
+	src.errs <- unix.EBADF
+	ev := nextEvent(t, events)
+	assert.Equal(t, PortRemoved, ev.Type)
+	assert.Equal(t, filepath.Join(e.devRoot, "ttyACM0"), ev.Port.Name)
+	assert.True(t, src.closed.Load())
+
+	require.NoError(t, os.Remove(filepath.Join(e.sysRoot, "class/tty/ttyS2")))
+	ev = nextEvent(t, events)
+	assert.Equal(t, PortRemoved, ev.Type)
+	assert.Equal(t, filepath.Join(e.devRoot, "ttyS2"), ev.Port.Name)
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok, "unexpected event")
+	case <-time.After(time.Second):
+		assert.Fail(t, "events channel is not closed")
+	}
+}
+
+func TestParseUevent(t *testing.T) {
+	msg := "add@/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0\x00" +
+		"ACTION=add\x00DEVPATH=/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0\x00" +
+		"SUBSYSTEM=tty\x00MAJOR=188\x00MINOR=0\x00DEVNAME=ttyUSB0\x00SEQNUM=4242\x00"
+	assert.Equal(t, uevent{
+		"ACTION":    "add",
+		"DEVPATH":   "/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0",
+		"SUBSYSTEM": "tty",
+		"MAJOR":     "188",
+		"MINOR":     "0",
+		"DEVNAME":   "ttyUSB0",
+		"SEQNUM":    "4242",
+	}, parseUevent([]byte(msg)))
+
+	assert.Nil(t, parseUevent(nil))
+	assert.Nil(t, parseUevent([]byte("libudev\x00\xfe\xed\xca\xfe")))
+	assert.Nil(t, parseUevent([]byte("add@/devices/virtual/tty/tty0\x00SUBSYSTEM=tty\x00")))
+}
+
+func TestNetlinkUevents_Cancel(t *testing.T) {
+	src, err := newNetlinkUevents()
+	if err != nil {
+		t.Skipf("netlink uevents not available: %v", err)
+	}
+	defer src.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// Some events might be received if devices are changing on the host
+	for {
+		if _, err = src.receive(ctx); err != nil {
+			break
+		}
+	}
+	assert.ErrorIs(t, err, context.Canceled)
+}
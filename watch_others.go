@@ -0,0 +1,19 @@
+//
+// Copyright 2019-2022 Veniamin Albaev <albenik@gmail.com>.
+// All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+//
+
+//go:build !linux
+
+package serial
+
+import "context"
+
+// Watch reports the serial ports arrival and removal until ctx is done, the returned channel is closed then.
+// The ports present when Watch is called are not reported, call GetDetailedPortsList() after Watch() returns
+// to get them without missing any event. The ports list is polled every second on this platform.
+func Watch(ctx context.Context) (<-chan PortEvent, error) {
+	return pollPorts(ctx, GetDetailedPortsList, watchPollInterval)
+}
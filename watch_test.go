@@ -0,0 +1,75 @@
+package serial
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollPorts(t *testing.T) {
+	ttyS0 := &PortDetails{Name: "/dev/ttyS0"}
+	ttyUSB0 := &PortDetails{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001"}
+	ttyUSB1 := &PortDetails{Name: "/dev/ttyUSB1", IsUSB: true, VID: "10c4", PID: "ea60"}
+	lists := make(chan []*PortDetails)
+	last := []*PortDetails{ttyS0, ttyUSB0}
+	list := func() ([]*PortDetails, error) {
+		select {
+		case l := <-lists:
+			if l == nil {
+				return nil, errors.New("enumeration failed")
+			}
+			last = l
+		default:
+		}
+		return last, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := pollPorts(ctx, list, time.Millisecond)
+	require.NoError(t, err)
+
+	lists <- nil // failed enumeration is skipped
+	lists <- []*PortDetails{ttyS0, ttyUSB1}
+	var got []PortEvent
+	for len(got) < 3 {
+		select {
+		case ev := <-events:
+			got = append(got, ev)
+		case <-time.After(time.Second):
+			require.FailNow(t, "no event")
+		}
+		if len(got) == 2 {
+			lists <- []*PortDetails{ttyS0}
+		}
+	}
+	assert.Equal(t, []PortEvent{
+		{Type: PortRemoved, Port: ttyUSB0},
+		{Type: PortAdded, Port: ttyUSB1},
+		{Type: PortRemoved, Port: ttyUSB1},
+	}, got)
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok, "unexpected event")
+	case <-time.After(time.Second):
+		assert.Fail(t, "events channel is not closed")
+	}
+}
+
+func TestPollPorts_Error(t *testing.T) {
+	failed := errors.New("enumeration failed")
+	_, err := pollPorts(context.Background(), func() ([]*PortDetails, error) { return nil, failed }, time.Second)
+	assert.ErrorIs(t, err, failed)
+}
+
+func TestPortEventType_String(t *testing.T) {
+	assert.Equal(t, "added", PortAdded.String())
+	assert.Equal(t, "removed", PortRemoved.String())
+	assert.Equal(t, "PortEventType(0)", PortEventType(0).String())
+}
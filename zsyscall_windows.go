@@ -42,17 +42,23 @@ var (
 	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
 
 	procRegEnumValueW       = modadvapi32.NewProc("RegEnumValueW")
+	procCancelIoEx          = modkernel32.NewProc("CancelIoEx")
+	procClearCommBreak      = modkernel32.NewProc("ClearCommBreak")
 	procClearCommError      = modkernel32.NewProc("ClearCommError")
 	procCreateEventW        = modkernel32.NewProc("CreateEventW")
 	procEscapeCommFunction  = modkernel32.NewProc("EscapeCommFunction")
+	procFlushFileBuffers    = modkernel32.NewProc("FlushFileBuffers")
 	procGetCommModemStatus  = modkernel32.NewProc("GetCommModemStatus")
 	procGetCommState        = modkernel32.NewProc("GetCommState")
+	procGetCommTimeouts     = modkernel32.NewProc("GetCommTimeouts")
 	procGetOverlappedResult = modkernel32.NewProc("GetOverlappedResult")
 	procPurgeComm           = modkernel32.NewProc("PurgeComm")
 	procResetEvent          = modkernel32.NewProc("ResetEvent")
+	procSetCommBreak        = modkernel32.NewProc("SetCommBreak")
 	procSetCommMask         = modkernel32.NewProc("SetCommMask")
 	procSetCommState        = modkernel32.NewProc("SetCommState")
 	procSetCommTimeouts     = modkernel32.NewProc("SetCommTimeouts")
+	procWaitCommEvent       = modkernel32.NewProc("WaitCommEvent")
 )
 
 func regEnumValue(key syscall.Handle, index uint32, name *uint16, nameLen *uint32, reserved *uint32, class *uint16, value *uint16, valueLen *uint32) (regerrno error) {
@@ -63,6 +69,22 @@ func regEnumValue(key syscall.Handle, index uint32, name *uint16, nameLen *uint3
 	return
 }
 
+func cancelIoEx(handle syscall.Handle, overlapped *syscall.Overlapped) (err error) {
+	r1, _, e1 := syscall.Syscall(procCancelIoEx.Addr(), 2, uintptr(handle), uintptr(unsafe.Pointer(overlapped)), 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
+func clearCommBreak(handle syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procClearCommBreak.Addr(), 1, uintptr(handle), 0, 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
 func clearCommError(handle syscall.Handle, lpErrors *uint32, lpStat *comstat) (err error) {
 	r1, _, e1 := syscall.Syscall(procClearCommError.Addr(), 3, uintptr(handle), uintptr(unsafe.Pointer(lpErrors)), uintptr(unsafe.Pointer(lpStat)))
 	if r1 == 0 {
@@ -94,6 +116,14 @@ func escapeCommFunction(handle syscall.Handle, function uint32) (res bool) {
 	return
 }
 
+func flushFileBuffers(handle syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procFlushFileBuffers.Addr(), 1, uintptr(handle), 0, 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
 func getCommModemStatus(handle syscall.Handle, bits *uint32) (res bool) {
 	r0, _, _ := syscall.Syscall(procGetCommModemStatus.Addr(), 2, uintptr(handle), uintptr(unsafe.Pointer(bits)), 0)
 	res = r0 != 0
@@ -108,6 +138,14 @@ func getCommState(handle syscall.Handle, dcb *dcb) (err error) {
 	return
 }
 
+func getCommTimeouts(handle syscall.Handle, timeouts *commTimeouts) (err error) {
+	r1, _, e1 := syscall.Syscall(procGetCommTimeouts.Addr(), 2, uintptr(handle), uintptr(unsafe.Pointer(timeouts)), 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
 func getOverlappedResult(handle syscall.Handle, overlapEvent *syscall.Overlapped, n *uint32, wait bool) (err error) {
 	var _p0 uint32
 	if wait {
@@ -136,6 +174,14 @@ func resetEvent(handle syscall.Handle) (err error) {
 	return
 }
 
+func setCommBreak(handle syscall.Handle) (err error) {
+	r1, _, e1 := syscall.Syscall(procSetCommBreak.Addr(), 1, uintptr(handle), 0, 0)
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}
+
 func setCommMask(handle syscall.Handle, mask uint32) (err error) {
 	r1, _, e1 := syscall.Syscall(procSetCommMask.Addr(), 2, uintptr(handle), uintptr(mask), 0)
 	if r1 == 0 {
@@ -159,3 +205,11 @@ func setCommTimeouts(handle syscall.Handle, timeouts *commTimeouts) (err error)
 	}
 	return
 }
+
+func waitCommEvent(handle syscall.Handle, mask *uint32, overlapped *syscall.Overlapped) (err error) {
+	r1, _, e1 := syscall.Syscall(procWaitCommEvent.Addr(), 3, uintptr(handle), uintptr(unsafe.Pointer(mask)), uintptr(unsafe.Pointer(overlapped)))
+	if r1 == 0 {
+		err = errnoErr(e1)
+	}
+	return
+}